@@ -0,0 +1,107 @@
+// Copyright 2019 Cuttle.ai. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package sdk
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/cuttle-ai/db-toolkit/datastores/services"
+	"github.com/cuttle-ai/go-sdk/httpclient"
+	"github.com/cuttle-ai/go-sdk/internal/platform"
+	"github.com/jinzhu/gorm"
+)
+
+//datastoresService is the name of the data-integration services in the discovery service
+const datastoresService = "Brain-Data-Integeration-Service"
+
+//DatastoresClient is the client of the data-integration services managing the datastores
+type DatastoresClient struct {
+	p *platform.Platform
+}
+
+//CreatedDatastore is the datastore created along with the idempotency key with which it was created
+type CreatedDatastore struct {
+	//Datastore is the datastore created
+	Datastore *services.Service
+	//IdempotencyKey is the key with which the creation was sent to the instances. The data-integration service
+	//detects the creations sent again with the same key as duplicates
+	IdempotencyKey string
+}
+
+//List returns the list of data stores available in the platform.
+//The call is hedged across the instances if hedging is set for the operation
+func (c *DatastoresClient) List(ctx context.Context) ([]services.Service, error) {
+	result := []services.Service{}
+	err := c.p.Invoke(ctx, platform.Call{
+		Service:   datastoresService,
+		Operation: "datastores.ListDatastores",
+		Method:    http.MethodGet,
+		Path:      "/services/datastore/list",
+		Out:       &result,
+		Hedged:    true,
+		Action:    "get the list of services",
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+//Stream calls fn for each of the data stores available in the platform without buffering the whole list.
+//Errors returned by fn stop the stream and are returned as such
+func (c *DatastoresClient) Stream(ctx context.Context, fn func(services.Service) error) error {
+	return c.p.Invoke(ctx, platform.Call{
+		Service:   datastoresService,
+		Operation: "datastores.StreamDatastores",
+		Method:    http.MethodGet,
+		Path:      "/services/datastore/list",
+		Element:   func() interface{} { return &services.Service{} },
+		Each:      func(s interface{}) error { return fn(*s.(*services.Service)) },
+		Action:    "stream the list of services",
+	})
+}
+
+//Get returns the info of data store provided in the platform. serviceID is the id of the service.
+//The call is hedged across the instances if hedging is set for the operation
+func (c *DatastoresClient) Get(ctx context.Context, serviceID uint) (*services.Service, error) {
+	result := &services.Service{}
+	err := c.p.Invoke(ctx, platform.Call{
+		Service:   datastoresService,
+		Operation: "datastores.GetDatastore",
+		Method:    http.MethodPost,
+		Path:      "/services/datastore/get",
+		Payload:   services.Service{Model: gorm.Model{ID: serviceID}},
+		Out:       result,
+		Hedged:    true,
+		Action:    "get the info of service",
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+//Create creates a datastore and returns it along with the idempotency key of the creation.
+//The idempotency key set in ctx with httpclient.WithIdempotencyKey is sent with the retries and to every instance tried,
+//it is generated if missing. Set the key to retry a failed creation without duplicating the datastore
+func (c *DatastoresClient) Create(ctx context.Context, service services.Service) (*CreatedDatastore, error) {
+	//the key is ensured here so that the one seen by the instances can be returned
+	ctx = httpclient.EnsureIdempotencyKey(ctx)
+	result := &services.Service{}
+	err := c.p.Invoke(ctx, platform.Call{
+		Service:   datastoresService,
+		Operation: "datastores.CreateDatastore",
+		Method:    http.MethodPost,
+		Path:      "/services/datastore/create",
+		Payload:   service,
+		Out:       result,
+		Action:    "create the service",
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &CreatedDatastore{Datastore: result, IdempotencyKey: httpclient.IdempotencyKey(ctx)}, nil
+}
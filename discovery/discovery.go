@@ -7,19 +7,69 @@
 package discovery
 
 import (
+	"context"
+	"sync"
+
 	"github.com/cuttle-ai/brain/log"
+	"github.com/cuttle-ai/go-sdk/httpclient"
+	"github.com/cuttle-ai/go-sdk/metrics"
+	"github.com/cuttle-ai/go-sdk/tracing"
 	"github.com/hashicorp/consul/api"
 )
 
+//ProxyService is the name with which the proxy of the discovery service is set in the httpclient
+const ProxyService = "discovery"
+
 //GetServices will return the services of the given name
 func GetServices(config *api.Config, name string, l log.Log) ([]*api.AgentService, error) {
+	return GetServicesContext(context.Background(), config, name, l)
+}
+
+//Resolver finds the instances of the platform services
+type Resolver interface {
+	//GetServices returns the instances of the service of the given name recording the lookup as a span of the trace in ctx
+	GetServices(ctx context.Context, name string, l log.Log) ([]*api.AgentService, error)
+}
+
+//Consul is the resolver finding the instances of the services registered in the consul agent.
+//Its client is created on the first lookup and reused for the following ones
+type Consul struct {
+	config *api.Config
+	once   sync.Once
+	client *api.Client
+	err    error
+}
+
+//NewConsul returns the resolver finding the services in the consul agent with the config
+func NewConsul(config *api.Config) *Consul {
+	return &Consul{config: config}
+}
+
+//GetServicesContext will return the services of the given name recording the lookup as a span of the trace in ctx.
+//A new consul client is created with the config for the lookup, reuse a Consul resolver to avoid it
+func GetServicesContext(ctx context.Context, config *api.Config, name string, l log.Log) ([]*api.AgentService, error) {
+	return NewConsul(config).GetServices(ctx, name, l)
+}
+
+//GetServices will return the services of the given name recording the lookup as a span of the trace in ctx.
+//The instances are routed in the httpclient as per their unix socket and protocol metadata.
+//The discovery service is called through the httpclient's proxy of the ProxyService
+func (c *Consul) GetServices(ctx context.Context, name string, l log.Log) (serviceList []*api.AgentService, err error) {
+	_, span := tracing.Start(ctx, "discovery.GetServices")
+	defer func() {
+		span.SetAttribute("service.name", name)
+		span.SetAttribute("instances", len(serviceList))
+		span.RecordError(err)
+		span.End()
+	}()
+
 	/*
 	 * We initialize the client
 	 * Then we get the list of services
 	 * Then will find the service with the given name
 	 */
 	//initializing the client
-	client, err := api.NewClient(config)
+	client, err := c.getClient()
 	if err != nil {
 		//error while initializing the client
 		l.Error("error while initializing the client for finding the service", name)
@@ -35,11 +85,29 @@ func GetServices(config *api.Config, name string, l log.Log) ([]*api.AgentServic
 	}
 
 	//iterating through the services to find the service with the given name
-	serviceList := []*api.AgentService{}
+	serviceList = []*api.AgentService{}
 	for _, v := range services {
 		if v.ID == name {
 			serviceList = append(serviceList, v)
+			//the instances served on unix sockets or over h2c are reached as per their metadata
+			httpclient.RouteInstance(v.Address, v.Port, v.Meta)
 		}
 	}
+	metrics.SetDiscoveredInstances(name, len(serviceList))
 	return serviceList, nil
 }
+
+//getClient returns the consul client of the resolver creating it on the first call
+func (c *Consul) getClient() (*api.Client, error) {
+	c.once.Do(func() {
+		config := c.config
+		if config.Transport != nil && config.HttpClient == nil {
+			cp := *config
+			cp.Transport = config.Transport.Clone()
+			cp.Transport.Proxy = httpclient.ServiceProxy(ProxyService)
+			config = &cp
+		}
+		c.client, c.err = api.NewClient(config)
+	})
+	return c.client, c.err
+}
@@ -15,4 +15,6 @@ require (
 	github.com/gojektech/valkyrie v0.0.0-20190210220504-8f62c1e7ba45 // indirect
 	github.com/hashicorp/consul/api v1.4.0
 	github.com/jinzhu/gorm v1.9.12
+	github.com/prometheus/client_golang v1.5.1
+	golang.org/x/net v0.0.0-20190620200207-3b0461eec859
 )
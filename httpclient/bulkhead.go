@@ -0,0 +1,114 @@
+// Copyright 2019 Cuttle.ai. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package httpclient
+
+import (
+	"context"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/cuttle-ai/go-sdk/metrics"
+)
+
+//Bulkhead caps the calls in flight to a platform service so that a slow service can't hold up
+//all the goroutines and connections of the application
+type Bulkhead struct {
+	//MaxConcurrent is the maximum number of calls in flight to the service. Defaults to 1
+	MaxConcurrent int
+	//QueueTimeout is the maximum time a call waits for a slot when the bulkhead is full before being rejected
+	//with ErrBulkheadFull. Calls are rejected right away if it's 0
+	QueueTimeout time.Duration
+}
+
+//semaphore has the slots of a bulkhead
+type semaphore struct {
+	service string
+	limit   Bulkhead
+	slots   chan struct{}
+}
+
+var (
+	bulkheadsMu sync.RWMutex
+	bulkheads   = map[string]*semaphore{}
+)
+
+//SetBulkhead sets the bulkhead for the calls made to the platform service.
+//The calls in flight while it's replaced keep their slots in the previous one
+func SetBulkhead(service string, b Bulkhead) {
+	if b.MaxConcurrent <= 0 {
+		b.MaxConcurrent = 1
+	}
+	bulkheadsMu.Lock()
+	bulkheads[service] = &semaphore{service: service, limit: b, slots: make(chan struct{}, b.MaxConcurrent)}
+	bulkheadsMu.Unlock()
+}
+
+//RemoveBulkhead removes the bulkhead set for the platform service
+func RemoveBulkhead(service string) {
+	bulkheadsMu.Lock()
+	delete(bulkheads, service)
+	bulkheadsMu.Unlock()
+}
+
+//acquireBulkhead takes a slot of the bulkhead of the service in the context and returns the function releasing it.
+//It fails with ErrBulkheadFull if no slot is freed within the queue timeout or with the context's error if it is done while waiting
+func acquireBulkhead(ctx context.Context) (func(), error) {
+	service, operation := Operation(ctx)
+	bulkheadsMu.RLock()
+	s, ok := bulkheads[service]
+	bulkheadsMu.RUnlock()
+	if !ok {
+		return func() {}, nil
+	}
+
+	select {
+	case s.slots <- struct{}{}:
+		return s.release(), nil
+	default:
+	}
+	if s.limit.QueueTimeout <= 0 {
+		metrics.ObserveBulkheadRejected(service, operation)
+		return nil, ErrBulkheadFull
+	}
+
+	//waiting for a slot
+	timer := time.NewTimer(s.limit.QueueTimeout)
+	defer timer.Stop()
+	select {
+	case s.slots <- struct{}{}:
+		return s.release(), nil
+	case <-timer.C:
+		metrics.ObserveBulkheadRejected(service, operation)
+		return nil, ErrBulkheadFull
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+//release records the slot taken and returns the function freeing it
+func (s *semaphore) release() func() {
+	metrics.SetBulkheadInFlight(s.service, len(s.slots))
+	once := sync.Once{}
+	return func() {
+		once.Do(func() {
+			<-s.slots
+			metrics.SetBulkheadInFlight(s.service, len(s.slots))
+		})
+	}
+}
+
+//releasingBody releases the slot of the bulkhead held by the request once the response body is closed
+type releasingBody struct {
+	io.ReadCloser
+	release func()
+}
+
+//Close closes the response body and releases the slot
+func (r *releasingBody) Close() error {
+	err := r.ReadCloser.Close()
+	r.release()
+	return err
+}
@@ -0,0 +1,428 @@
+// Copyright 2019 Cuttle.ai. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package httpclient
+
+import (
+	"bytes"
+	"container/list"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+//CacheHeader is the header set on the responses served from the cache
+const CacheHeader = "X-From-Cache"
+
+//DefaultMaxCacheEntrySize is the size of the largest response body stored in the cache by default
+const DefaultMaxCacheEntrySize = 1 << 20
+
+//CacheStore stores the cached responses
+type CacheStore interface {
+	//Get returns the value stored for the key
+	Get(key string) ([]byte, bool)
+	//Set stores the value for the key
+	Set(key string, value []byte)
+	//Delete removes the value stored for the key
+	Delete(key string)
+}
+
+//CacheOptions are the options for caching the responses of the get requests
+type CacheOptions struct {
+	//Store is where the responses are cached. Defaults to a memory store of 1000 entries
+	Store CacheStore
+	//MaxEntrySize is the size of the largest response body that is cached. Defaults to DefaultMaxCacheEntrySize
+	MaxEntrySize int64
+	//Operations are the sdk operations like datastores.ListDatastores whose responses are cached. Only the reads should be
+	//listed, not the gets changing the platform like octopus.RemoveDict and octopus.UpdateDict. Nothing is cached if it's empty
+	Operations []string
+}
+
+//Caching returns the middleware that caches the responses of the get requests of the operations in the options as per their Cache-Control, ETag
+//and Last-Modified headers. Fresh responses are served from the store and the stale ones are revalidated with
+//If-None-Match and If-Modified-Since. The cache is partitioned per user on the access token and credentials sent with
+//the requests, and the responses of a service are shared across its instances. Responses served from the cache have the CacheHeader set
+func Caching(opts CacheOptions) Middleware {
+	if opts.Store == nil {
+		opts.Store = NewMemoryCache(1000)
+	}
+	if opts.MaxEntrySize <= 0 {
+		opts.MaxEntrySize = DefaultMaxCacheEntrySize
+	}
+	reads := map[string]bool{}
+	for _, op := range opts.Operations {
+		reads[op] = true
+	}
+	return func(next http.RoundTripper) http.RoundTripper {
+		return RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			key := cacheKey(req)
+			if req.Method != http.MethodGet && req.Method != http.MethodHead {
+				//the cached response is outdated by the unsafe requests to the resource
+				res, err := next.RoundTrip(req)
+				if err == nil && mutating(req.Method) && res.StatusCode < http.StatusBadRequest {
+					opts.Store.Delete(key)
+				}
+				return res, err
+			}
+			reqCC := cacheControl(req.Header)
+			_, operation := Operation(req.Context())
+			if req.Method != http.MethodGet || !reads[operation] || req.Header.Get("Range") != "" || reqCC.has("no-store") {
+				return next.RoundTrip(req)
+			}
+
+			/*
+			 * First we will look for the response in the cache
+			 * Then we will serve it if it's fresh
+			 * Then we will revalidate it if it's stale
+			 * Then we will cache the new response
+			 */
+			//looking for the response in the cache
+			entry := loadCacheEntry(opts.Store, key, req)
+
+			//serving the fresh response
+			if entry != nil && entry.fresh(reqCC) {
+				return entry.response(req), nil
+			}
+
+			//revalidating the stale response
+			if entry != nil {
+				req = req.Clone(req.Context())
+				if etag := entry.Header.Get("ETag"); etag != "" {
+					req.Header.Set("If-None-Match", etag)
+				}
+				if lm := entry.Header.Get("Last-Modified"); lm != "" {
+					req.Header.Set("If-Modified-Since", lm)
+				}
+			}
+			res, err := next.RoundTrip(req)
+			if err != nil {
+				return nil, err
+			}
+			if entry != nil && res.StatusCode == http.StatusNotModified {
+				io.Copy(ioutil.Discard, res.Body)
+				res.Body.Close()
+				for k, v := range res.Header {
+					entry.Header[k] = v
+				}
+				entry.Stored = time.Now()
+				entry.save(opts.Store, key)
+				return entry.response(req), nil
+			}
+
+			//caching the new response
+			if !cacheable(res) {
+				opts.Store.Delete(key)
+				return res, nil
+			}
+			entry = &cacheEntry{StatusCode: res.StatusCode, Header: res.Header.Clone(), Stored: time.Now(), Vary: map[string]string{}}
+			for _, h := range strings.Split(res.Header.Get("Vary"), ",") {
+				if h = strings.TrimSpace(h); h != "" {
+					entry.Vary[http.CanonicalHeaderKey(h)] = req.Header.Get(h)
+				}
+			}
+			res.Body = &cachingBody{ReadCloser: res.Body, entry: entry, store: opts.Store, key: key, max: opts.MaxEntrySize}
+			return res, nil
+		})
+	}
+}
+
+//cacheKey returns the key of the cached response for the request. It is partitioned on the credentials of the request.
+//The responses of the requests made for an operation are shared across the instances of its service
+func cacheKey(req *http.Request) string {
+	h := sha256.New()
+	for _, c := range req.Cookies() {
+		h.Write([]byte(c.Name + "=" + c.Value + ";"))
+	}
+	h.Write([]byte(req.Header.Get("Authorization")))
+	partition := hex.EncodeToString(h.Sum(nil)[:16])
+	target := req.URL.Host
+	if service, _ := Operation(req.Context()); service != "unknown" {
+		target = service
+	}
+	return partition + " " + target + req.URL.RequestURI()
+}
+
+//cacheEntry is a cached response
+type cacheEntry struct {
+	StatusCode int
+	Header     http.Header
+	Body       []byte
+	Stored     time.Time
+	//Vary has the values of the request headers by which the response varies
+	Vary map[string]string
+}
+
+//loadCacheEntry returns the cached response for the request. It will be nil if there is none matching the request
+func loadCacheEntry(store CacheStore, key string, req *http.Request) *cacheEntry {
+	b, ok := store.Get(key)
+	if !ok {
+		return nil
+	}
+	entry := &cacheEntry{}
+	if err := json.Unmarshal(b, entry); err != nil {
+		store.Delete(key)
+		return nil
+	}
+	for h, v := range entry.Vary {
+		if req.Header.Get(h) != v {
+			return nil
+		}
+	}
+	return entry
+}
+
+//save stores the entry
+func (e *cacheEntry) save(store CacheStore, key string) {
+	b, err := json.Marshal(e)
+	if err != nil {
+		return
+	}
+	store.Set(key, b)
+}
+
+//fresh checks whether the cached response can be served without revalidating it
+func (e *cacheEntry) fresh(reqCC directives) bool {
+	resCC := cacheControl(e.Header)
+	if resCC.has("no-cache") || reqCC.has("no-cache") || strings.Contains(e.Header.Get("Pragma"), "no-cache") {
+		return false
+	}
+	age := time.Since(e.Stored)
+	if a, err := strconv.Atoi(e.Header.Get("Age")); err == nil {
+		age += time.Duration(a) * time.Second
+	}
+	if maxAge, ok := reqCC.seconds("max-age"); ok && age > maxAge {
+		return false
+	}
+	if maxAge, ok := resCC.seconds("max-age"); ok {
+		return age < maxAge
+	}
+	expires, err := http.ParseTime(e.Header.Get("Expires"))
+	if err != nil {
+		return false
+	}
+	date, err := http.ParseTime(e.Header.Get("Date"))
+	if err != nil {
+		date = e.Stored
+	}
+	return age < expires.Sub(date)
+}
+
+//response returns the cached response for the request
+func (e *cacheEntry) response(req *http.Request) *http.Response {
+	header := e.Header.Clone()
+	header.Set(CacheHeader, "1")
+	return &http.Response{
+		Status:        strconv.Itoa(e.StatusCode) + " " + http.StatusText(e.StatusCode),
+		StatusCode:    e.StatusCode,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        header,
+		Body:          ioutil.NopCloser(bytes.NewReader(e.Body)),
+		ContentLength: int64(len(e.Body)),
+		Request:       req,
+	}
+}
+
+//cacheable checks whether the response can be stored in the cache.
+//Only the successful responses with a freshness lifetime or a validator are stored
+func cacheable(res *http.Response) bool {
+	if res.StatusCode != http.StatusOK {
+		return false
+	}
+	cc := cacheControl(res.Header)
+	if cc.has("no-store") || res.Header.Get("Vary") == "*" {
+		return false
+	}
+	_, maxAge := cc.seconds("max-age")
+	return maxAge || res.Header.Get("Expires") != "" || res.Header.Get("ETag") != "" || res.Header.Get("Last-Modified") != ""
+}
+
+//cachingBody captures the response body while it's being read and stores the response once it's read completely
+type cachingBody struct {
+	io.ReadCloser
+	entry *cacheEntry
+	store CacheStore
+	key   string
+	max   int64
+	buf   bytes.Buffer
+	skip  bool
+}
+
+//Read reads from the response body capturing it
+func (c *cachingBody) Read(p []byte) (int, error) {
+	n, err := c.ReadCloser.Read(p)
+	if !c.skip {
+		if int64(c.buf.Len()+n) > c.max {
+			//the response is too large to be cached
+			c.skip = true
+			c.buf = bytes.Buffer{}
+		} else {
+			c.buf.Write(p[:n])
+		}
+	}
+	if err == io.EOF && !c.skip {
+		c.skip = true
+		c.entry.Body = c.buf.Bytes()
+		c.entry.save(c.store, c.key)
+	}
+	return n, err
+}
+
+//directives are the directives of a Cache-Control header
+type directives map[string]string
+
+//cacheControl parses the Cache-Control header
+func cacheControl(h http.Header) directives {
+	d := directives{}
+	for _, v := range strings.Split(h.Get("Cache-Control"), ",") {
+		kv := strings.SplitN(strings.TrimSpace(v), "=", 2)
+		if kv[0] == "" {
+			continue
+		}
+		d[strings.ToLower(kv[0])] = ""
+		if len(kv) == 2 {
+			d[strings.ToLower(kv[0])] = strings.Trim(kv[1], `"`)
+		}
+	}
+	return d
+}
+
+//has checks whether the directive is present
+func (d directives) has(name string) bool {
+	_, ok := d[name]
+	return ok
+}
+
+//seconds returns the duration of the directive given in seconds
+func (d directives) seconds(name string) (time.Duration, bool) {
+	v, ok := d[name]
+	if !ok {
+		return 0, false
+	}
+	s, err := strconv.Atoi(v)
+	if err != nil {
+		return 0, false
+	}
+	return time.Duration(s) * time.Second, true
+}
+
+//MemoryCache is a cache store in memory that evicts the least recently used entries beyond its capacity
+type MemoryCache struct {
+	mu       sync.Mutex
+	capacity int
+	entries  map[string]*list.Element
+	order    *list.List
+}
+
+//memoryEntry is an entry of the memory cache
+type memoryEntry struct {
+	key   string
+	value []byte
+}
+
+//NewMemoryCache returns a memory cache store holding up to capacity entries
+func NewMemoryCache(capacity int) *MemoryCache {
+	return &MemoryCache{capacity: capacity, entries: map[string]*list.Element{}, order: list.New()}
+}
+
+//Get returns the value stored for the key
+func (m *MemoryCache) Get(key string) ([]byte, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	e, ok := m.entries[key]
+	if !ok {
+		return nil, false
+	}
+	m.order.MoveToFront(e)
+	return e.Value.(*memoryEntry).value, true
+}
+
+//Set stores the value for the key evicting the least recently used entry if the cache is full
+func (m *MemoryCache) Set(key string, value []byte) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if e, ok := m.entries[key]; ok {
+		e.Value.(*memoryEntry).value = value
+		m.order.MoveToFront(e)
+		return
+	}
+	m.entries[key] = m.order.PushFront(&memoryEntry{key: key, value: value})
+	if m.capacity > 0 && m.order.Len() > m.capacity {
+		last := m.order.Back()
+		m.order.Remove(last)
+		delete(m.entries, last.Value.(*memoryEntry).key)
+	}
+}
+
+//Delete removes the value stored for the key
+func (m *MemoryCache) Delete(key string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if e, ok := m.entries[key]; ok {
+		m.order.Remove(e)
+		delete(m.entries, key)
+	}
+}
+
+//DiskCache is a cache store keeping the entries as files in a directory
+type DiskCache struct {
+	dir string
+}
+
+//NewDiskCache returns a disk cache store in the directory. The directory is created if it doesn't exist
+func NewDiskCache(dir string) (*DiskCache, error) {
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, err
+	}
+	return &DiskCache{dir: dir}, nil
+}
+
+//path returns the path of the file of the key
+func (d *DiskCache) path(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return filepath.Join(d.dir, hex.EncodeToString(sum[:]))
+}
+
+//Get returns the value stored for the key
+func (d *DiskCache) Get(key string) ([]byte, bool) {
+	b, err := ioutil.ReadFile(d.path(key))
+	if err != nil {
+		return nil, false
+	}
+	return b, true
+}
+
+//Set stores the value for the key. The file is replaced atomically so that readers never see a partial entry
+func (d *DiskCache) Set(key string, value []byte) {
+	f, err := ioutil.TempFile(d.dir, "entry-")
+	if err != nil {
+		return
+	}
+	_, err = f.Write(value)
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		os.Remove(f.Name())
+		return
+	}
+	if err := os.Rename(f.Name(), d.path(key)); err != nil {
+		os.Remove(f.Name())
+	}
+}
+
+//Delete removes the value stored for the key
+func (d *DiskCache) Delete(key string) {
+	os.Remove(d.path(key))
+}
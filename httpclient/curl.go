@@ -0,0 +1,106 @@
+// Copyright 2019 Cuttle.ai. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package httpclient
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync/atomic"
+)
+
+//maxCurlBody is the size of the largest non json body rendered in the curl commands
+const maxCurlBody = 4 << 10
+
+var curlErrors int32
+
+//SetCurlErrors enables or disables attaching the curl command of the failed requests to the APIError returned for them
+func SetCurlErrors(enabled bool) {
+	v := int32(0)
+	if enabled {
+		v = 1
+	}
+	atomic.StoreInt32(&curlErrors, v)
+}
+
+//Curl renders the request as an equivalent curl command to reproduce it. The cookie values, authorization headers
+//and the DefaultSensitiveFields in the query and json body are redacted, so the command can be shared in logs and errors.
+//The body is read with the request's GetBody, streamed bodies are not rendered
+func Curl(req *http.Request) string {
+	r := redactor{fields: map[string]bool{}}
+	for _, f := range DefaultSensitiveFields {
+		r.fields[strings.ToLower(f)] = true
+	}
+	return r.curl(req)
+}
+
+//curl renders the request as a curl command with the sensitive information redacted
+func (r redactor) curl(req *http.Request) string {
+	b := &strings.Builder{}
+	b.WriteString("curl -X " + req.Method + " " + shellQuote(r.url(req.URL)))
+
+	//rendering the headers
+	keys := make([]string, 0, len(req.Header))
+	for k := range req.Header {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		for _, v := range req.Header[k] {
+			b.WriteString(" -H " + shellQuote(k+": "+r.headerValue(k, v)))
+		}
+	}
+
+	//rendering the body
+	if req.Body == nil || req.Body == http.NoBody {
+		return b.String()
+	}
+	if req.GetBody == nil {
+		b.WriteString(" --data-binary " + shellQuote("<streamed body>"))
+		return b.String()
+	}
+	body, err := req.GetBody()
+	if err != nil {
+		return b.String()
+	}
+	defer body.Close()
+	raw, _ := ioutil.ReadAll(body)
+	var v interface{}
+	switch {
+	case json.Unmarshal(raw, &v) == nil:
+		redacted, _ := json.Marshal(r.redact(v))
+		b.WriteString(" --data-raw " + shellQuote(string(redacted)))
+	case len(raw) > maxCurlBody:
+		b.WriteString(" --data-binary " + shellQuote("<"+strconv.Itoa(len(raw))+" bytes of body>"))
+	default:
+		b.WriteString(" --data-binary " + shellQuote(string(raw)))
+	}
+	return b.String()
+}
+
+//headerValue returns the value of the header with the cookie values and authorization redacted
+func (r redactor) headerValue(key, value string) string {
+	switch http.CanonicalHeaderKey(key) {
+	case "Cookie":
+		//the names of the cookies are kept to know which of them have to be set to reproduce the request
+		cookies := strings.Split(value, ";")
+		for i, c := range cookies {
+			name := strings.SplitN(strings.TrimSpace(c), "=", 2)[0]
+			cookies[i] = name + "=" + Redacted
+		}
+		return strings.Join(cookies, "; ")
+	case "Authorization", "Proxy-Authorization":
+		return Redacted
+	}
+	return value
+}
+
+//shellQuote quotes the string for a posix shell
+func shellQuote(s string) string {
+	return "'" + strings.Replace(s, "'", `'\''`, -1) + "'"
+}
@@ -0,0 +1,99 @@
+// Copyright 2019 Cuttle.ai. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package httpclient
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"strconv"
+	"sync/atomic"
+	"time"
+)
+
+//DeadlineHeader is the header in which the time left for an attempt is sent to the platform services in milliseconds.
+//The services can use it to drop the work whose caller would have given up by the time it's done
+const DeadlineHeader = "X-Request-Deadline"
+
+//DefaultMinAttemptBudget is the least time given to an attempt or an instance by default when splitting the budget of a call
+const DefaultMinAttemptBudget = 200 * time.Millisecond
+
+var minAttemptBudget = int64(DefaultMinAttemptBudget)
+
+//SetMinAttemptBudget sets the least time given to an attempt or an instance when the time left till the deadline of a call
+//is split across its retries and instances, so that the first attempts aren't too short to succeed.
+//A budget <= 0 restores DefaultMinAttemptBudget
+func SetMinAttemptBudget(d time.Duration) {
+	if d <= 0 {
+		d = DefaultMinAttemptBudget
+	}
+	atomic.StoreInt64(&minAttemptBudget, int64(d))
+}
+
+//share returns the time given to one of the parts when the time left till the deadline is split into parts
+func share(deadline time.Time, parts int) time.Duration {
+	left := time.Until(deadline)
+	if parts < 1 {
+		parts = 1
+	}
+	s := left / time.Duration(parts)
+	if min := time.Duration(atomic.LoadInt64(&minAttemptBudget)); s < min {
+		s = min
+	}
+	if s > left {
+		s = left
+	}
+	return s
+}
+
+//BudgetContext returns a copy of the context whose deadline is its share of the time left when it is split into parts.
+//The calls to the instances of a service are made with it, parts being the instances yet to be called including
+//the current one, so that an instance not responding leaves the time for the rest to be tried.
+//If the context has no deadline, it is returned as such
+func BudgetContext(ctx context.Context, parts int) (context.Context, context.CancelFunc) {
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		return ctx, func() {}
+	}
+	return context.WithTimeout(ctx, share(deadline, parts))
+}
+
+//budget is the middleware that splits the time left till the deadline of the call across the attempts left
+//and sends the budget of the attempt in the DeadlineHeader. The budget is also bounded by the timeout of the attempt
+func budget(next http.RoundTripper) http.RoundTripper {
+	return RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		deadline, ok := callDeadline(req.Context())
+		if !ok {
+			return next.RoundTrip(req)
+		}
+		if time.Until(deadline) <= 0 {
+			return nil, context.DeadlineExceeded
+		}
+		ctx, cancel := context.WithTimeout(req.Context(), share(deadline, attemptsLeft(req.Context())))
+		deadline, _ = ctx.Deadline()
+		req = req.Clone(ctx)
+		req.Header.Set(DeadlineHeader, strconv.FormatInt(int64(time.Until(deadline)/time.Millisecond), 10))
+		res, err := next.RoundTrip(req)
+		if err != nil {
+			cancel()
+			return nil, err
+		}
+		res.Body = &cancelBody{ReadCloser: res.Body, cancel: cancel}
+		return res, nil
+	})
+}
+
+//cancelBody cancels the context of the attempt once the response body is closed
+type cancelBody struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+//Close closes the response body and cancels the context of the attempt
+func (c *cancelBody) Close() error {
+	err := c.ReadCloser.Close()
+	c.cancel()
+	return err
+}
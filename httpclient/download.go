@@ -0,0 +1,250 @@
+// Copyright 2019 Cuttle.ai. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package httpclient
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+//DefaultDownloadResumes is the number of times an interrupted download is resumed by default
+const DefaultDownloadResumes = 3
+
+//ErrChecksumMismatch is returned when the checksum of the downloaded content doesn't match the expected one
+var ErrChecksumMismatch = errors.New("checksum of the downloaded content doesn't match")
+
+//DownloadOptions are the options for downloading a response body
+type DownloadOptions struct {
+	//SHA256 is the hex encoded sha256 checksum expected for the content. If empty, the sha-256 Digest header
+	//of the response is verified when the server sends one
+	SHA256 string
+	//MaxResumes is the number of times an interrupted download is resumed. Defaults to DefaultDownloadResumes, a negative value disables resuming
+	MaxResumes int
+	//Progress if not nil is called with the bytes written so far
+	Progress ProgressFunc
+}
+
+//Download streams the body of the response for a get request of the url to w without buffering it
+//using the default DownloadOptions. It returns the number of bytes written
+func (c *Client) Download(ctx context.Context, url string, w io.Writer) (int64, error) {
+	return c.DownloadWithOptions(ctx, url, w, DownloadOptions{})
+}
+
+//DownloadWithOptions streams the body of the response for a get request of the url to w without buffering it.
+//The length of the content is verified against the Content-Length of the response along with its checksum.
+//Interrupted downloads are resumed from the bytes already written with Range requests, guarded by the ETag or Last-Modified
+//of the first response. The download has no timeout other than the deadline of ctx. It returns the number of bytes written
+func (c *Client) DownloadWithOptions(ctx context.Context, url string, w io.Writer, opts DownloadOptions) (int64, error) {
+	if opts.MaxResumes == 0 {
+		opts.MaxResumes = DefaultDownloadResumes
+	}
+	d := &download{c: c, url: url, w: &downloadWriter{w: w, h: sha256.New(), progress: opts.Progress}, total: -1}
+	for resumes := 0; ; resumes++ {
+		err := d.fetch(ctx)
+		if err == nil {
+			break
+		}
+		var werr writeError
+		if errors.As(err, &werr) || ctx.Err() != nil || resumes >= opts.MaxResumes || !d.resumable(err) {
+			return d.w.n, err
+		}
+	}
+
+	//verifying the checksum
+	expected, actual := strings.ToLower(opts.SHA256), hex.EncodeToString(d.w.h.Sum(nil))
+	if expected == "" && d.digest != "" {
+		b, err := base64.StdEncoding.DecodeString(d.digest)
+		if err != nil {
+			return d.w.n, fmt.Errorf("invalid digest %s sent by the server: %w", d.digest, err)
+		}
+		expected = hex.EncodeToString(b)
+	}
+	if expected != "" && expected != actual {
+		return d.w.n, fmt.Errorf("%w: expected %s, got %s", ErrChecksumMismatch, expected, actual)
+	}
+	return d.w.n, nil
+}
+
+//download is the state of a download across its resumptions
+type download struct {
+	c         *Client
+	url       string
+	w         *downloadWriter
+	total     int64
+	validator string
+	digest    string
+}
+
+//fetch makes a request for the content not written yet and copies it to the writer
+func (d *download) fetch(ctx context.Context) error {
+	/*
+	 * First we will make the request for the rest of the content
+	 * Then we will check the response
+	 * Then we will copy the body to the writer
+	 */
+	//making the request
+	req, err := http.NewRequest(http.MethodGet, d.url, nil)
+	if err != nil {
+		return err
+	}
+	req = req.WithContext(ctx)
+	req.Header.Set("Accept", "*/*")
+	//the offsets of the ranges are of the raw content, so we don't want it to be compressed
+	req.Header.Set("Accept-Encoding", "identity")
+	if d.w.n > 0 {
+		req.Header.Set("Range", "bytes="+strconv.FormatInt(d.w.n, 10)+"-")
+		if d.validator != "" {
+			req.Header.Set("If-Range", d.validator)
+		}
+	}
+	res, apiErr, err := d.c.send(req, d.c.doOnce)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	//checking the response
+	switch {
+	case res.StatusCode == http.StatusPartialContent && d.w.n > 0:
+		start, total, err := contentRange(res.Header.Get("Content-Range"))
+		if err != nil || start != d.w.n || (d.total >= 0 && total >= 0 && total != d.total) {
+			apiErr.Err = fmt.Errorf("unexpected content range %q for the resumed download", res.Header.Get("Content-Range"))
+			return apiErr.failed()
+		}
+	case res.StatusCode == http.StatusOK:
+		if d.w.n == 0 {
+			d.total = res.ContentLength
+			d.digest = sha256Digest(res.Header.Get("Digest"))
+			d.validator = res.Header.Get("ETag")
+			if d.validator == "" {
+				d.validator = res.Header.Get("Last-Modified")
+			}
+			break
+		}
+		if res.Header.Get("ETag") != d.validator && res.Header.Get("Last-Modified") != d.validator {
+			//the If-Range didn't match, so the content has changed since the download started
+			apiErr.Err = errors.New("the content changed while it was being downloaded")
+			return apiErr.failed()
+		}
+		//the server sent the whole content, so we skip the part already written
+		if _, err := io.CopyN(ioutil.Discard, res.Body, d.w.n); err != nil {
+			apiErr.Err = err
+			return apiErr.failed()
+		}
+	default:
+		apiErr.Body, _ = readBody(res.Body, d.c.responseLimit())
+		if err := statusError(apiErr); err != nil {
+			return err
+		}
+		apiErr.Err = fmt.Errorf("unexpected status %d for the download", res.StatusCode)
+		return apiErr.failed()
+	}
+
+	//copying the body
+	_, err = io.Copy(d.w, res.Body)
+	if err == nil && d.total >= 0 && d.w.n < d.total {
+		err = io.ErrUnexpectedEOF
+	}
+	if err == nil && d.total >= 0 && d.w.n > d.total {
+		err = fmt.Errorf("received %d bytes more than the content length %d", d.w.n-d.total, d.total)
+	}
+	if err != nil {
+		if _, ok := err.(writeError); ok {
+			return err
+		}
+		apiErr.Err = err
+		return apiErr.failed()
+	}
+	return nil
+}
+
+//resumable checks whether the download can be resumed after the error.
+//Downloads are resumed only if the server sent a validator to make sure the content hasn't changed
+func (d *download) resumable(err error) bool {
+	apiErr := &APIError{}
+	if !errors.As(err, &apiErr) || rejectedLocally(apiErr.Err) || apiErr.StatusCode >= http.StatusBadRequest && apiErr.StatusCode < http.StatusInternalServerError {
+		return false
+	}
+	return d.w.n == 0 || d.validator != ""
+}
+
+//contentRange parses the start and the total length from the Content-Range header of a partial response.
+//The total will be -1 if it isn't known
+func contentRange(h string) (int64, int64, error) {
+	if !strings.HasPrefix(h, "bytes ") {
+		return 0, 0, fmt.Errorf("invalid content range %q", h)
+	}
+	parts := strings.SplitN(strings.TrimPrefix(h, "bytes "), "/", 2)
+	bounds := strings.SplitN(parts[0], "-", 2)
+	if len(parts) != 2 || len(bounds) != 2 {
+		return 0, 0, fmt.Errorf("invalid content range %q", h)
+	}
+	start, err := strconv.ParseInt(bounds[0], 10, 64)
+	if err != nil {
+		return 0, 0, err
+	}
+	if parts[1] == "*" {
+		return start, -1, nil
+	}
+	total, err := strconv.ParseInt(parts[1], 10, 64)
+	return start, total, err
+}
+
+//sha256Digest returns the base64 sha-256 digest from the Digest header of the response. It will be empty if there is none
+func sha256Digest(h string) string {
+	for _, d := range strings.Split(h, ",") {
+		kv := strings.SplitN(strings.TrimSpace(d), "=", 2)
+		if len(kv) == 2 && strings.EqualFold(kv[0], "sha-256") {
+			return kv[1]
+		}
+	}
+	return ""
+}
+
+//writeError is the error from the writer of a download. Such errors are not resumed
+type writeError struct {
+	err error
+}
+
+func (w writeError) Error() string {
+	return w.err.Error()
+}
+
+//Unwrap returns the error from the writer
+func (w writeError) Unwrap() error {
+	return w.err
+}
+
+//downloadWriter writes the content to the writer of the download while hashing and counting it
+type downloadWriter struct {
+	w        io.Writer
+	h        hash.Hash
+	n        int64
+	progress ProgressFunc
+}
+
+//Write writes to the writer of the download
+func (d *downloadWriter) Write(p []byte) (int, error) {
+	n, err := d.w.Write(p)
+	d.h.Write(p[:n])
+	d.n += int64(n)
+	if n > 0 && d.progress != nil {
+		d.progress(d.n)
+	}
+	if err != nil {
+		return n, writeError{err: err}
+	}
+	return n, nil
+}
@@ -0,0 +1,151 @@
+// Copyright 2019 Cuttle.ai. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package httpclient
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync/atomic"
+
+	"github.com/gojektech/heimdall"
+)
+
+var (
+	//ErrNotFound is matched by the errors when the requested resource doesn't exist in the platform
+	ErrNotFound = errors.New("resource not found")
+	//ErrUnauthorized is matched by the errors when the token is missing, invalid or not permitted for the resource
+	ErrUnauthorized = errors.New("unauthorized")
+	//ErrConflict is matched by the errors when the request conflicts with the existing state of the resource
+	ErrConflict = errors.New("conflict")
+	//ErrUnavailable is matched by the errors when no instance of the service could serve the request
+	ErrUnavailable = errors.New("service unavailable")
+	//ErrResponseTooLarge is returned when the response body exceeds the maximum response size
+	ErrResponseTooLarge = errors.New("response too large")
+	//ErrRateLimited is returned when a call is rejected by a fail fast rate limit of the sdk
+	ErrRateLimited = errors.New("rate limited by the sdk")
+	//ErrBulkheadFull is returned when a call is rejected since the service has the maximum calls in flight allowed by its bulkhead
+	ErrBulkheadFull = errors.New("bulkhead of the service is full")
+)
+
+//APIError is the error returned when a request to a platform service fails.
+//It can be compared with the Err sentinels of the package using errors.Is
+type APIError struct {
+	//StatusCode is the http status of the response. It will be 0 if no response was received
+	StatusCode int
+	//Message is the message sent by the server in the response envelope
+	Message string
+	//URL is the target url of the request
+	URL string
+	//Instance is the host:port of the service instance that was called
+	Instance string
+	//IdempotencyKey is the idempotency key with which a mutating request was sent.
+	//A failed request with a key can be retried with the same key without the risk of duplicating it
+	IdempotencyKey string
+	//Curl is the curl command reproducing the request with the secrets redacted. It is set only if enabled with SetCurlErrors
+	Curl string
+	//Body is the raw body of the response
+	Body []byte
+	//Err is the underlying error if the request couldn't be completed or the response couldn't be read
+	Err error
+	//req is the request rendered as the Curl once it fails
+	req *http.Request
+}
+
+//failed returns the api error of the failed request with the curl command reproducing it if enabled with SetCurlErrors.
+//The command is rendered only for the failures since the body of the request is read again to render it
+func (e *APIError) failed() *APIError {
+	if e.req != nil && atomic.LoadInt32(&curlErrors) == 1 {
+		e.Curl = Curl(e.req)
+	}
+	e.req = nil
+	return e
+}
+
+//Error returns the error message
+func (e *APIError) Error() string {
+	if e.Err != nil {
+		return fmt.Sprintf("request to %s failed: %v", e.URL, e.Err)
+	}
+	if e.StatusCode == 0 {
+		return fmt.Sprintf("request to %s failed: %s", e.URL, e.Message)
+	}
+	return fmt.Sprintf("request to %s failed with status %d: %s", e.URL, e.StatusCode, e.Message)
+}
+
+//Unwrap returns the underlying error of the api error
+func (e *APIError) Unwrap() error {
+	return e.Err
+}
+
+//Is reports whether the api error matches the target sentinel error
+func (e *APIError) Is(target error) bool {
+	switch target {
+	case ErrNotFound:
+		return e.StatusCode == http.StatusNotFound
+	case ErrUnauthorized:
+		return e.StatusCode == http.StatusUnauthorized || e.StatusCode == http.StatusForbidden
+	case ErrConflict:
+		return e.StatusCode == http.StatusConflict
+	case ErrUnavailable:
+		if e.StatusCode == 0 {
+			return !rejectedLocally(e.Err)
+		}
+		return e.StatusCode >= http.StatusInternalServerError
+	}
+	return false
+}
+
+//rejectedLocally checks whether the request was not sent because the sdk or the caller stopped it.
+//Such requests shouldn't be failed over to the other instances
+func rejectedLocally(err error) bool {
+	return errors.Is(err, ErrRateLimited) || errors.Is(err, ErrBulkheadFull) || errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded)
+}
+
+//FailOver checks whether the call to an instance failing with err can be failed over to the next instance.
+//ctx is the context of the whole call. Calls failing with ErrUnavailable or since their share of the time left ran out
+//are failed over till ctx is done
+func FailOver(ctx context.Context, err error) bool {
+	if ctx.Err() != nil {
+		return false
+	}
+	return errors.Is(err, ErrUnavailable) || errors.Is(err, context.DeadlineExceeded)
+}
+
+//retriedError is the error of a request failing in all its attempts. The retries keep only the messages of the errors,
+//so it unwraps to the error of the last attempt to be matched with errors.Is
+type retriedError struct {
+	message string
+	last    error
+}
+
+//Error returns the messages of the errors of all the attempts
+func (e *retriedError) Error() string {
+	return e.message
+}
+
+//Unwrap returns the error of the last attempt
+func (e *retriedError) Unwrap() error {
+	return e.last
+}
+
+//lastErrorDoer records the error of the last attempt made with the doer
+type lastErrorDoer struct {
+	heimdall.Doer
+	last error
+}
+
+//Do makes the request with the doer recording its error
+func (l *lastErrorDoer) Do(req *http.Request) (*http.Response, error) {
+	res, err := l.Doer.Do(req)
+	l.last = err
+	return res, err
+}
+
+//NoInstancesError returns the error to be returned when none of the instances of the service could be found
+func NoInstancesError(service string) error {
+	return &APIError{Message: "no instances of " + service + " are available"}
+}
@@ -0,0 +1,110 @@
+// Copyright 2019 Cuttle.ai. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package httpclient
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+//ErrInjectedFault is matched by the errors of the requests failed by the fault injection middleware
+var ErrInjectedFault = errors.New("fault injected by the sdk")
+
+//Fault is a fault injected into the requests made to the platform services for chaos testing
+type Fault struct {
+	//Service is the platform service whose requests are affected. All the services are affected if it's empty
+	Service string
+	//Path is the prefix of the path of the requests affected. All the paths are affected if it's empty
+	Path string
+	//Rate is the fraction of the matching requests affected, from 0 to 1
+	Rate float64
+	//Latency is the delay added before sending the request
+	Latency time.Duration
+	//StatusCode if not 0 is the status of the response returned without sending the request
+	StatusCode int
+	//Err if not nil is returned without sending the request
+	Err error
+	//DropConnection sends the request and fails it as if the connection was dropped before the response was read,
+	//to test that the requests processed by a service aren't duplicated
+	DropConnection bool
+}
+
+//matches checks whether the fault affects the request
+func (f Fault) matches(req *http.Request) bool {
+	if f.Service != "" {
+		if service, _ := Operation(req.Context()); service != f.Service {
+			return false
+		}
+	}
+	return strings.HasPrefix(req.URL.Path, f.Path)
+}
+
+//FaultInjection returns the middleware that injects the faults into the attempts matching them at their rates.
+//Every matching fault is rolled for independently in the given order, the latencies adding up till one of them fails
+//the attempt. Requests failed with Err or DropConnection fail with an error matching ErrInjectedFault
+func FaultInjection(faults ...Fault) Middleware {
+	return func(next http.RoundTripper) http.RoundTripper {
+		return RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			for _, f := range faults {
+				if !f.matches(req) || rand.Float64() >= f.Rate {
+					continue
+				}
+
+				//adding the latency
+				if f.Latency > 0 {
+					timer := time.NewTimer(f.Latency)
+					select {
+					case <-timer.C:
+					case <-req.Context().Done():
+						timer.Stop()
+						return nil, req.Context().Err()
+					}
+				}
+
+				//failing the request
+				switch {
+				case f.Err != nil:
+					return nil, fmt.Errorf("%w: %v", ErrInjectedFault, f.Err)
+				case f.StatusCode != 0:
+					return faultResponse(req, f.StatusCode), nil
+				case f.DropConnection:
+					res, err := next.RoundTrip(req)
+					if err != nil {
+						return nil, err
+					}
+					io.Copy(ioutil.Discard, res.Body)
+					res.Body.Close()
+					return nil, fmt.Errorf("%w: connection to %s dropped", ErrInjectedFault, req.URL.Host)
+				}
+			}
+			return next.RoundTrip(req)
+		})
+	}
+}
+
+//faultResponse returns the response with the injected status and the message envelope
+func faultResponse(req *http.Request, statusCode int) *http.Response {
+	body, _ := json.Marshal(Message{Message: ErrInjectedFault.Error()})
+	return &http.Response{
+		Status:        strconv.Itoa(statusCode) + " " + http.StatusText(statusCode),
+		StatusCode:    statusCode,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        http.Header{"Content-Type": []string{"application/json"}},
+		Body:          ioutil.NopCloser(bytes.NewReader(body)),
+		ContentLength: int64(len(body)),
+		Request:       req,
+	}
+}
@@ -0,0 +1,235 @@
+// Copyright 2019 Cuttle.ai. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package httpclient
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+//ErrNoInteraction is the error with which a replaying fixture fails the requests that none of its interactions match
+var ErrNoInteraction = errors.New("no recorded interaction matches the request")
+
+//FixtureMode is the mode in which a fixture is used
+type FixtureMode int
+
+const (
+	//Replay serves the requests from the interactions recorded in the fixture without calling the platform
+	Replay FixtureMode = iota
+	//Record calls the platform and records the interactions to the fixture
+	Record
+)
+
+//FixtureBody is a recorded request or response body. JSON bodies are kept as such so that the fixtures stay readable
+type FixtureBody struct {
+	//JSON is the body if it is a valid json
+	JSON json.RawMessage `json:",omitempty"`
+	//Text is the body if it isn't a json
+	Text string `json:",omitempty"`
+}
+
+//Interaction is a request made to a platform service and its response as recorded in a fixture
+type Interaction struct {
+	//Method is the http method of the request
+	Method string
+	//URL is the path and query of the request. The host is not recorded since the instances differ across runs
+	URL string
+	//RequestBody is the body of the request
+	RequestBody FixtureBody
+	//StatusCode is the http status of the response
+	StatusCode int
+	//Header has the headers of the response
+	Header http.Header `json:",omitempty"`
+	//ResponseBody is the body of the response
+	ResponseBody FixtureBody
+}
+
+//Fixture records the interactions with the platform services to a file and replays them in the tests.
+//The cookies, authorization headers and DefaultSensitiveFields are scrubbed from the recorded interactions
+type Fixture struct {
+	path         string
+	mode         FixtureMode
+	r            redactor
+	mu           sync.Mutex
+	interactions []Interaction
+	used         []bool
+}
+
+//NewFixture returns the fixture stored at the path. In Replay mode the interactions are loaded from the file
+func NewFixture(path string, mode FixtureMode) (*Fixture, error) {
+	f := &Fixture{path: path, mode: mode, r: redactor{fields: map[string]bool{}}}
+	for _, field := range DefaultSensitiveFields {
+		f.r.fields[strings.ToLower(field)] = true
+	}
+	if mode == Record {
+		return f, nil
+	}
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(b, &f.interactions); err != nil {
+		return nil, fmt.Errorf("couldn't decode the fixture %s: %w", path, err)
+	}
+	f.used = make([]bool, len(f.interactions))
+	return f, nil
+}
+
+//Interactions returns the interactions recorded or loaded in the fixture
+func (f *Fixture) Interactions() []Interaction {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]Interaction(nil), f.interactions...)
+}
+
+//Save writes the recorded interactions to the fixture file. It does nothing in Replay mode
+func (f *Fixture) Save() error {
+	if f.mode != Record {
+		return nil
+	}
+	f.mu.Lock()
+	b, err := json.MarshalIndent(f.interactions, "", "\t")
+	f.mu.Unlock()
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(f.path), 0755); err != nil {
+		return err
+	}
+	return ioutil.WriteFile(f.path, append(b, '\n'), 0644)
+}
+
+//Middleware returns the middleware that records the attempts going through it or replays them from the fixture.
+//Replayed requests are matched by their method, url and body, streamed bodies being matched as empty. Identical requests are served the recorded
+//interactions in order, the last one being repeated once all of them are used
+func (f *Fixture) Middleware() Middleware {
+	return func(next http.RoundTripper) http.RoundTripper {
+		return RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			body, err := requestBody(req)
+			if err != nil {
+				return nil, err
+			}
+			u := &url.URL{Path: req.URL.Path, RawQuery: req.URL.RawQuery}
+			in := Interaction{Method: req.Method, URL: f.r.url(u), RequestBody: f.scrub(body)}
+			if f.mode == Replay {
+				return f.replay(req, in)
+			}
+
+			res, err := next.RoundTrip(req)
+			if err != nil {
+				return nil, err
+			}
+			b, err := ioutil.ReadAll(res.Body)
+			res.Body.Close()
+			if err != nil {
+				return nil, err
+			}
+			res.Body = ioutil.NopCloser(bytes.NewReader(b))
+			in.StatusCode = res.StatusCode
+			in.Header = f.scrubHeader(res.Header)
+			in.ResponseBody = f.scrub(b)
+			f.mu.Lock()
+			f.interactions = append(f.interactions, in)
+			f.mu.Unlock()
+			return res, nil
+		})
+	}
+}
+
+//replay returns the response of the recorded interaction matching the request
+func (f *Fixture) replay(req *http.Request, in Interaction) (*http.Response, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	match := -1
+	for i, v := range f.interactions {
+		if v.Method != in.Method || v.URL != in.URL || !f.sameBody(v.RequestBody, in.RequestBody) {
+			continue
+		}
+		match = i
+		if !f.used[i] {
+			break
+		}
+	}
+	if match == -1 {
+		return nil, fmt.Errorf("%w: %s %s", ErrNoInteraction, in.Method, in.URL)
+	}
+	f.used[match] = true
+	rec := f.interactions[match]
+	b := []byte(rec.ResponseBody.Text)
+	if len(rec.ResponseBody.JSON) > 0 {
+		b = rec.ResponseBody.JSON
+	}
+	header := rec.Header.Clone()
+	if header == nil {
+		header = http.Header{}
+	}
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", rec.StatusCode, http.StatusText(rec.StatusCode)),
+		StatusCode:    rec.StatusCode,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        header,
+		Body:          ioutil.NopCloser(bytes.NewReader(b)),
+		ContentLength: int64(len(b)),
+		Request:       req,
+	}, nil
+}
+
+//sameBody checks whether the recorded body matches the body of the request ignoring the json formatting
+func (f *Fixture) sameBody(recorded, body FixtureBody) bool {
+	if len(recorded.JSON) == 0 || len(body.JSON) == 0 {
+		return len(recorded.JSON) == len(body.JSON) && recorded.Text == body.Text
+	}
+	return bytes.Equal(f.scrub(recorded.JSON).JSON, body.JSON)
+}
+
+//requestBody returns the body of the request without consuming it. Streamed bodies are not read
+func requestBody(req *http.Request) ([]byte, error) {
+	if req.Body == nil || req.Body == http.NoBody || req.GetBody == nil {
+		return nil, nil
+	}
+	body, err := req.GetBody()
+	if err != nil {
+		return nil, err
+	}
+	defer body.Close()
+	return ioutil.ReadAll(body)
+}
+
+//scrub returns the body to be recorded with the sensitive json fields redacted
+func (f *Fixture) scrub(b []byte) FixtureBody {
+	if len(b) == 0 {
+		return FixtureBody{}
+	}
+	var v interface{}
+	if err := json.Unmarshal(b, &v); err != nil {
+		return FixtureBody{Text: string(b)}
+	}
+	res, _ := json.Marshal(f.r.redact(v))
+	return FixtureBody{JSON: res}
+}
+
+//scrubHeader returns the response headers to be recorded with the cookies and authorization redacted.
+//The content length is dropped since scrubbing may change the length of the body
+func (f *Fixture) scrubHeader(h http.Header) http.Header {
+	c := h.Clone()
+	for _, k := range sensitiveHeaders {
+		if _, ok := c[k]; ok {
+			c.Set(k, Redacted)
+		}
+	}
+	c.Del("Content-Length")
+	return c
+}
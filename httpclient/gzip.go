@@ -0,0 +1,122 @@
+// Copyright 2019 Cuttle.ai. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package httpclient
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync/atomic"
+)
+
+var compressionThreshold int64
+
+//SetRequestCompressionThreshold sets the size in bytes above which the request bodies are gzipped and sent
+//with the gzip Content-Encoding. The platform services called must accept gzipped bodies. A threshold <= 0 disables it,
+//which is the default
+func SetRequestCompressionThreshold(n int64) {
+	atomic.StoreInt64(&compressionThreshold, n)
+}
+
+//compression is the innermost middleware that asks for gzipped responses and decodes them transparently.
+//It also gzips the request bodies above the compression threshold
+func compression(next http.RoundTripper) http.RoundTripper {
+	return RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		/*
+		 * First we will compress the request body if required
+		 * Then we will ask for a gzipped response
+		 * Then we will decode the response if it was gzipped
+		 */
+		//compressing the request body
+		req = req.Clone(req.Context())
+		threshold := atomic.LoadInt64(&compressionThreshold)
+		//streamed bodies are not compressed since that would buffer them
+		if threshold > 0 && req.Body != nil && req.Body != http.NoBody && req.GetBody != nil && req.Header.Get("Content-Encoding") == "" {
+			if err := compressBody(req, threshold); err != nil {
+				return nil, err
+			}
+		}
+
+		//asking for a gzipped response
+		if req.Header.Get("Accept-Encoding") != "" || req.Header.Get("Range") != "" {
+			//the caller has asked for a specific encoding or a range of the raw body, so we leave it as such
+			return next.RoundTrip(req)
+		}
+		req.Header.Set("Accept-Encoding", "gzip")
+		res, err := next.RoundTrip(req)
+		if err != nil {
+			return nil, err
+		}
+
+		//decoding the gzipped response
+		if !strings.EqualFold(res.Header.Get("Content-Encoding"), "gzip") {
+			return res, nil
+		}
+		res.Body = &gzipBody{body: res.Body}
+		res.Header.Del("Content-Encoding")
+		res.Header.Del("Content-Length")
+		res.ContentLength = -1
+		res.Uncompressed = true
+		return res, nil
+	})
+}
+
+//compressBody gzips the body of the request if it's larger than the threshold
+func compressBody(req *http.Request, threshold int64) error {
+	body, err := ioutil.ReadAll(req.Body)
+	req.Body.Close()
+	if err != nil {
+		return err
+	}
+	if int64(len(body)) <= threshold {
+		req.Body = ioutil.NopCloser(bytes.NewReader(body))
+		req.ContentLength = int64(len(body))
+		return nil
+	}
+	buf := &bytes.Buffer{}
+	w := gzip.NewWriter(buf)
+	if _, err := w.Write(body); err != nil {
+		return err
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+	compressed := buf.Bytes()
+	req.Body = ioutil.NopCloser(bytes.NewReader(compressed))
+	req.GetBody = func() (io.ReadCloser, error) {
+		return ioutil.NopCloser(bytes.NewReader(compressed)), nil
+	}
+	req.ContentLength = int64(len(compressed))
+	req.Header.Set("Content-Encoding", "gzip")
+	req.Header.Set("Content-Length", strconv.Itoa(len(compressed)))
+	return nil
+}
+
+//gzipBody decodes the gzipped response body lazily so that empty bodies don't fail
+type gzipBody struct {
+	body io.ReadCloser
+	zr   *gzip.Reader
+	err  error
+}
+
+//Read reads the decoded body
+func (g *gzipBody) Read(p []byte) (int, error) {
+	if g.zr == nil && g.err == nil {
+		g.zr, g.err = gzip.NewReader(g.body)
+	}
+	if g.err != nil {
+		return 0, g.err
+	}
+	return g.zr.Read(p)
+}
+
+//Close closes the gzipped body
+func (g *gzipBody) Close() error {
+	return g.body.Close()
+}
@@ -0,0 +1,173 @@
+// Copyright 2019 Cuttle.ai. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package httpclient
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+)
+
+//HedgeOptions are the options for hedging the read calls made to the platform services
+type HedgeOptions struct {
+	//Delay after which the call is sent to the next instance if none of the calls in flight have responded
+	Delay time.Duration
+	//P95 makes the observed p95 latency of the operation the delay once enough calls have succeeded.
+	//Delay is used till then
+	P95 bool
+	//MaxHedges is the maximum number of extra calls that can be in flight. Defaults to 1
+	MaxHedges int
+}
+
+//minLatencySamples is the number of latencies to be observed before the p95 latency is used as the hedge delay
+const minLatencySamples = 20
+
+//maxLatencySamples is the number of recent latencies kept to compute the p95 latency
+const maxLatencySamples = 100
+
+//hedging is the hedging set for an operation along with its observed latencies
+type hedging struct {
+	opts      HedgeOptions
+	mu        sync.Mutex
+	latencies []time.Duration
+	next      int
+}
+
+//observe records the latency of a successful call
+func (h *hedging) observe(d time.Duration) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if len(h.latencies) < maxLatencySamples {
+		h.latencies = append(h.latencies, d)
+		return
+	}
+	h.latencies[h.next] = d
+	h.next = (h.next + 1) % maxLatencySamples
+}
+
+//delay returns the delay after which the next instance is to be called
+func (h *hedging) delay() time.Duration {
+	if !h.opts.P95 {
+		return h.opts.Delay
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if len(h.latencies) < minLatencySamples {
+		return h.opts.Delay
+	}
+	sorted := append([]time.Duration{}, h.latencies...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+	return sorted[len(sorted)*95/100]
+}
+
+var (
+	hedgingsMu sync.RWMutex
+	hedgings   = map[string]*hedging{}
+)
+
+//SetHedging enables hedging for the sdk operation like datastores.GetDatastore of the platform service.
+//Only the read operations that can be served by any instance are hedged
+func SetHedging(service, operation string, opts HedgeOptions) {
+	if opts.MaxHedges <= 0 {
+		opts.MaxHedges = 1
+	}
+	hedgingsMu.Lock()
+	hedgings[service+"/"+operation] = &hedging{opts: opts}
+	hedgingsMu.Unlock()
+}
+
+//RemoveHedging disables hedging for the sdk operation of the platform service
+func RemoveHedging(service, operation string) {
+	hedgingsMu.Lock()
+	delete(hedgings, service+"/"+operation)
+	hedgingsMu.Unlock()
+}
+
+//FirstSuccess calls the instances 0 to n-1 one after the other till one of them succeeds and returns its index.
+//If hedging is set for the operation in the context, the next instance is also called when the ones in flight haven't
+//responded within the hedge delay and the first success is used cancelling the others.
+//Calls are failed over as per FailOver. An error not failed over is returned once the calls in flight have failed too
+func FirstSuccess(ctx context.Context, n int, call func(ctx context.Context, i int) error) (int, error) {
+	/*
+	 * First we will get the hedging of the operation
+	 * Then we will call the first instance
+	 * Then we will wait for the results calling the next instances on failures and hedge delays
+	 */
+	//getting the hedging
+	service, operation := Operation(ctx)
+	hedgingsMu.RLock()
+	h := hedgings[service+"/"+operation]
+	hedgingsMu.RUnlock()
+
+	//calling the first instance
+	type result struct {
+		i       int
+		err     error
+		latency time.Duration
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	results := make(chan result, n)
+	next, inFlight := 0, 0
+	launch := func() {
+		if next >= n {
+			return
+		}
+		i := next
+		next++
+		inFlight++
+		callCtx, callCancel := BudgetContext(ctx, n-i)
+		go func() {
+			defer callCancel()
+			start := time.Now()
+			err := call(callCtx, i)
+			results <- result{i: i, err: err, latency: time.Since(start)}
+		}()
+	}
+	launch()
+
+	//waiting for the results
+	var lastErr, stopErr error
+	for inFlight > 0 {
+		var timer *time.Timer
+		var hedgeC <-chan time.Time
+		if h != nil && stopErr == nil && next < n && inFlight <= h.opts.MaxHedges {
+			timer = time.NewTimer(h.delay())
+			hedgeC = timer.C
+		}
+		select {
+		case r := <-results:
+			inFlight--
+			if r.err == nil {
+				if h != nil {
+					h.observe(r.latency)
+				}
+				return r.i, nil
+			}
+			switch {
+			case !FailOver(ctx, r.err):
+				//the hedges in flight may still succeed, so no more instances are called but they are waited for
+				if stopErr == nil {
+					stopErr = r.err
+				}
+			case stopErr == nil:
+				lastErr = r.err
+				launch()
+			}
+		case <-hedgeC:
+			launch()
+		case <-ctx.Done():
+			return -1, ctx.Err()
+		}
+		if timer != nil {
+			timer.Stop()
+		}
+	}
+	if stopErr != nil {
+		return -1, stopErr
+	}
+	return -1, lastErr
+}
@@ -23,15 +23,36 @@ type Message struct {
 	Data interface{}
 }
 
-type myHTTPClient struct {
-	token    string
-	tokenKey string
-	domain   string
+//retryCount is the number of times a failed request is retried
+const retryCount = 4
+
+//Client is the http client with retry mechanisms that authenticates every request
+//with the platform's token cookie
+type Client struct {
+	token           string
+	tokenKey        string
+	domain          string
+	middlewares     []Middleware
+	maxResponseSize int64
 }
 
-func (c *myHTTPClient) Do(request *http.Request) (*http.Response, error) {
-	cookie := http.Cookie{Name: c.tokenKey, Value: c.token, Domain: c.domain, Path: "/"}
-	request.AddCookie(&cookie)
+//NewClient returns a new client that sets the token as the cookie with tokenKey for the given domain.
+//The domain can be empty for a client calling many instances, the cookie is then set for the host of each request
+func NewClient(domain, token, tokenKey string) *Client {
+	return &Client{
+		token:    token,
+		tokenKey: tokenKey,
+		domain:   domain,
+	}
+}
+
+//Do makes the request with the auth cookie and retry mechanisms.
+//The request id and traceparent of the request's context are sent as headers, they are generated if missing.
+//Mutating requests are sent with the idempotency key of the context or a generated one, reused across the retries.
+//The request waits for its turn if the operation in the context is rate limited and for a slot if its service has a bulkhead.
+//If the context has a deadline, the time left is split across the attempts and sent to the service in the DeadlineHeader.
+//Requests whose context is done fail with the context's error
+func (c *Client) Do(request *http.Request) (*http.Response, error) {
 	initalTimeout := 2 * time.Millisecond
 	maxTimeout := 9 * time.Millisecond
 	exponentFactor := float64(2)
@@ -39,58 +60,90 @@ func (c *myHTTPClient) Do(request *http.Request) (*http.Response, error) {
 	backoff := heimdall.NewExponentialBackoff(initalTimeout, maxTimeout, exponentFactor, maximumJitterInterval)
 	retrier := heimdall.NewRetrier(backoff)
 	timeout := 1000 * time.Millisecond
+	doer := &lastErrorDoer{Doer: &http.Client{Timeout: timeout, Transport: c.transport()}}
 	client := heimdallC.NewClient(
-		heimdallC.WithHTTPTimeout(timeout),
+		heimdallC.WithHTTPClient(doer),
 		heimdallC.WithRetrier(retrier),
-		heimdallC.WithRetryCount(4),
+		heimdallC.WithRetryCount(retryCount),
 	)
-	return client.Do(request)
+	return c.run(request, retryCount+1, func(request *http.Request) (*http.Response, error) {
+		res, err := client.Do(request)
+		if err != nil && doer.last != nil {
+			err = &retriedError{message: err.Error(), last: doer.last}
+		}
+		return res, err
+	})
 }
 
-//Get makes a get request to a api url with retry mechanisms
-func Get(domain, url, token, tokenKey string) (*http.Response, error) {
-	/*
-	 * First we will initalize the client
-	 * Then we will send the get request
-	 * Then we will return the response
-	 */
-	//initalizing the client
-	client := heimdallC.NewClient(
-		heimdallC.WithHTTPClient(&myHTTPClient{
-			token:    token,
-			tokenKey: tokenKey,
-			domain:   domain,
-		}),
-	)
+//doOnce makes the request like Do but without retrying it so that its body can be streamed without buffering.
+//The request has no timeout other than the deadline of its context
+func (c *Client) doOnce(request *http.Request) (*http.Response, error) {
+	client := &http.Client{Transport: c.transport()}
+	return c.run(request, 1, client.Do)
+}
 
-	//then we will make the request
-	res, err := client.Get(url, http.Header{})
+//run prepares the request to be sent in the given number of attempts and sends it. The request holds a slot of its service's bulkhead till the response body is closed
+func (c *Client) run(request *http.Request, attempts int, send func(*http.Request) (*http.Response, error)) (*http.Response, error) {
+	request, err := c.prepare(request, attempts)
 	if err != nil {
 		return nil, err
 	}
-
-	//return the response
+	release, err := acquireBulkhead(request.Context())
+	if err != nil {
+		return nil, err
+	}
+	res, err := send(request)
+	if err != nil {
+		release()
+		//the retries keep only the messages of the errors, so the caller giving up is told from the context
+		if ctxErr := request.Context().Err(); ctxErr != nil {
+			return nil, ctxErr
+		}
+		return nil, err
+	}
+	res.Body = &releasingBody{ReadCloser: res.Body, release: release}
 	return res, nil
 }
 
-//Post makes a post request to a api url with retry mechanisms
-func Post(domain, url, token, tokenKey string, body io.Reader) (*http.Response, error) {
+//prepare returns the request with the request context, headers and auth cookie to be sent to the platform.
+//It waits for the turn of the request if the operation in the context is rate limited
+func (c *Client) prepare(request *http.Request, attempts int) (*http.Request, error) {
+	request = request.WithContext(withAttemptCounter(EnsureRequestContext(request.Context()), attempts))
+	if err := waitRateLimit(request.Context()); err != nil {
+		return nil, err
+	}
+	setRequestHeaders(request)
+	setIdempotencyKey(request)
+	domain := c.domain
+	if domain == "" {
+		domain = request.URL.Hostname()
+	}
+	cookie := http.Cookie{Name: c.tokenKey, Value: c.token, Domain: domain, Path: "/"}
+	request.AddCookie(&cookie)
+	return request, nil
+}
+
+//send makes a request of the given method to the api url with retry mechanisms
+func send(method, domain, url, token, tokenKey string, body io.Reader) (*http.Response, error) {
 	/*
 	 * First we will initalize the client
-	 * Then we will send the post request
+	 * Then we will create the request
+	 * Then we will send the request
 	 * Then we will return the response
 	 */
 	//initalizing the client
 	client := heimdallC.NewClient(
-		heimdallC.WithHTTPClient(&myHTTPClient{
-			token:    token,
-			tokenKey: tokenKey,
-			domain:   domain,
-		}),
+		heimdallC.WithHTTPClient(NewClient(domain, token, tokenKey)),
 	)
 
+	//creating the request
+	req, err := http.NewRequest(method, url, body)
+	if err != nil {
+		return nil, err
+	}
+
 	//then we will make the request
-	res, err := client.Post(url, body, http.Header{})
+	res, err := client.Do(req)
 	if err != nil {
 		return nil, err
 	}
@@ -98,3 +151,28 @@ func Post(domain, url, token, tokenKey string, body io.Reader) (*http.Response,
 	//return the response
 	return res, nil
 }
+
+//Get makes a get request to a api url with retry mechanisms
+func Get(domain, url, token, tokenKey string) (*http.Response, error) {
+	return send(http.MethodGet, domain, url, token, tokenKey, nil)
+}
+
+//Post makes a post request to a api url with retry mechanisms
+func Post(domain, url, token, tokenKey string, body io.Reader) (*http.Response, error) {
+	return send(http.MethodPost, domain, url, token, tokenKey, body)
+}
+
+//Put makes a put request to a api url with retry mechanisms
+func Put(domain, url, token, tokenKey string, body io.Reader) (*http.Response, error) {
+	return send(http.MethodPut, domain, url, token, tokenKey, body)
+}
+
+//Patch makes a patch request to a api url with retry mechanisms
+func Patch(domain, url, token, tokenKey string, body io.Reader) (*http.Response, error) {
+	return send(http.MethodPatch, domain, url, token, tokenKey, body)
+}
+
+//Delete makes a delete request to a api url with retry mechanisms
+func Delete(domain, url, token, tokenKey string) (*http.Response, error) {
+	return send(http.MethodDelete, domain, url, token, tokenKey, nil)
+}
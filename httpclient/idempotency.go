@@ -0,0 +1,80 @@
+// Copyright 2019 Cuttle.ai. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package httpclient
+
+import (
+	"context"
+	"crypto/rand"
+	"fmt"
+	"net/http"
+)
+
+//IdempotencyKeyHeader is the header in which the idempotency key of a mutating request is sent to the platform services
+const IdempotencyKeyHeader = "Idempotency-Key"
+
+type idempotencyKey struct{}
+
+type noIdempotencyKey struct{}
+
+//WithIdempotencyKey returns a copy of the context carrying the idempotency key for the mutating requests made with it
+func WithIdempotencyKey(ctx context.Context, key string) context.Context {
+	return context.WithValue(ctx, idempotencyKey{}, key)
+}
+
+//IdempotencyKey returns the idempotency key carried by the context. It will be empty if the context has none
+func IdempotencyKey(ctx context.Context) string {
+	key, _ := ctx.Value(idempotencyKey{}).(string)
+	return key
+}
+
+//EnsureIdempotencyKey returns a copy of the context with an idempotency key. It is generated if missing.
+//Operations failing over across the instances ensure the key before the first attempt so that every instance sees the same key
+func EnsureIdempotencyKey(ctx context.Context) context.Context {
+	if IdempotencyKey(ctx) != "" {
+		return ctx
+	}
+	return WithIdempotencyKey(ctx, newIdempotencyKey())
+}
+
+//WithoutIdempotencyKey returns a copy of the context whose requests are sent without an idempotency key.
+//It is meant for the reads sent with a mutating method, which can be hedged to several instances at once
+func WithoutIdempotencyKey(ctx context.Context) context.Context {
+	return context.WithValue(ctx, noIdempotencyKey{}, true)
+}
+
+//newIdempotencyKey returns a random uuid v4 to be used as an idempotency key
+func newIdempotencyKey() string {
+	b := make([]byte, 16)
+	rand.Read(b)
+	b[6] = b[6]&0x0f | 0x40
+	b[8] = b[8]&0x3f | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:])
+}
+
+//mutating checks whether the method of the request modifies the resources in the platform
+func mutating(method string) bool {
+	switch method {
+	case http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete:
+		return true
+	}
+	return false
+}
+
+//setIdempotencyKey sets the idempotency key header of a mutating request if the caller hasn't set it or opted out of it.
+//The key is taken from the request's context or generated. It returns the key of the request
+func setIdempotencyKey(req *http.Request) string {
+	if skip, _ := req.Context().Value(noIdempotencyKey{}).(bool); skip || !mutating(req.Method) {
+		return ""
+	}
+	if key := req.Header.Get(IdempotencyKeyHeader); key != "" {
+		return key
+	}
+	key := IdempotencyKey(req.Context())
+	if key == "" {
+		key = newIdempotencyKey()
+	}
+	req.Header.Set(IdempotencyKeyHeader, key)
+	return key
+}
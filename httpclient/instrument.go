@@ -0,0 +1,118 @@
+// Copyright 2019 Cuttle.ai. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package httpclient
+
+import (
+	"context"
+	"net/http"
+	"sync/atomic"
+	"time"
+
+	"github.com/cuttle-ai/go-sdk/metrics"
+	"github.com/cuttle-ai/go-sdk/tracing"
+)
+
+type attemptKey struct{}
+
+type operationKey struct{}
+
+//operation is the sdk operation for which the requests are made
+type operation struct {
+	service string
+	name    string
+}
+
+//WithOperation returns a copy of the context carrying the platform service and the sdk operation
+//for which the requests are made. They are used to label the metrics of the requests
+func WithOperation(ctx context.Context, service, name string) context.Context {
+	return context.WithValue(ctx, operationKey{}, operation{service: service, name: name})
+}
+
+//Operation returns the platform service and the sdk operation carried by the context.
+//They will be unknown if the context has none
+func Operation(ctx context.Context) (service, name string) {
+	op, ok := ctx.Value(operationKey{}).(operation)
+	if !ok {
+		return "unknown", "unknown"
+	}
+	return op.service, op.name
+}
+
+//attempts counts the attempts made for a request
+type attempts struct {
+	n        int32
+	max      int
+	deadline time.Time
+}
+
+//withAttemptCounter returns a copy of the context with a counter of the attempts made for a request,
+//max being the number of attempts that can be made for it including the retries.
+//The deadline of the context is kept to be split across the attempts
+func withAttemptCounter(ctx context.Context, max int) context.Context {
+	a := &attempts{max: max}
+	a.deadline, _ = ctx.Deadline()
+	return context.WithValue(ctx, attemptKey{}, a)
+}
+
+//callDeadline returns the deadline of the call made for the request of the context.
+//It is the deadline of the context given by the caller, not of the attempt in progress
+func callDeadline(ctx context.Context) (time.Time, bool) {
+	a, ok := ctx.Value(attemptKey{}).(*attempts)
+	if !ok {
+		return ctx.Deadline()
+	}
+	return a.deadline, !a.deadline.IsZero()
+}
+
+//nextAttempt returns the retry number of the attempt being made for the request of the context
+func nextAttempt(ctx context.Context) int {
+	a, ok := ctx.Value(attemptKey{}).(*attempts)
+	if !ok {
+		return 0
+	}
+	return int(atomic.AddInt32(&a.n, 1)) - 1
+}
+
+//currentAttempt returns the retry number of the attempt in progress for the request of the context
+func currentAttempt(ctx context.Context) int {
+	a, ok := ctx.Value(attemptKey{}).(*attempts)
+	if !ok || atomic.LoadInt32(&a.n) == 0 {
+		return 0
+	}
+	return int(atomic.LoadInt32(&a.n)) - 1
+}
+
+//attemptsLeft returns the number of attempts that can still be made for the request of the context including the one in progress
+func attemptsLeft(ctx context.Context) int {
+	a, ok := ctx.Value(attemptKey{}).(*attempts)
+	if !ok {
+		return 1
+	}
+	return a.max - currentAttempt(ctx)
+}
+
+//instrumentAttempt records a span and the metrics for every attempt made to an instance including the retries
+func instrumentAttempt(next http.RoundTripper) http.RoundTripper {
+	return RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		ctx, span := tracing.Start(req.Context(), "httpclient.Attempt")
+		defer span.End()
+		span.SetAttribute("instance", req.URL.Host)
+		span.SetAttribute("http.method", req.Method)
+		span.SetAttribute("http.path", req.URL.Path)
+		span.SetAttribute("retry", nextAttempt(req.Context()))
+		service, name := Operation(ctx)
+		start := time.Now()
+
+		res, err := next.RoundTrip(req.WithContext(ctx))
+		if err != nil {
+			span.RecordError(err)
+			metrics.ObserveRequest(service, name, req.URL.Host, metrics.OutcomeError, time.Since(start))
+			return nil, err
+		}
+		span.SetAttribute("http.status_code", res.StatusCode)
+		metrics.ObserveRequest(service, name, req.URL.Host, metrics.Outcome(res.StatusCode), time.Since(start))
+		return res, nil
+	})
+}
@@ -0,0 +1,289 @@
+// Copyright 2019 Cuttle.ai. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package httpclient
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"sync/atomic"
+)
+
+//DefaultMaxResponseSize is the maximum size of a response body read by default
+const DefaultMaxResponseSize = 32 << 20
+
+var maxResponseSize int64 = DefaultMaxResponseSize
+
+//SetMaxResponseSize sets the maximum size of a response body that will be read by the clients.
+//Responses exceeding it fail with ErrResponseTooLarge. A size <= 0 restores DefaultMaxResponseSize
+func SetMaxResponseSize(n int64) {
+	if n <= 0 {
+		n = DefaultMaxResponseSize
+	}
+	atomic.StoreInt64(&maxResponseSize, n)
+}
+
+//SetMaxResponseSize sets the maximum size of a response body that will be read by the client
+//overriding the package level one. A size <= 0 makes the client use the package level one
+func (c *Client) SetMaxResponseSize(n int64) {
+	c.maxResponseSize = n
+}
+
+//responseLimit returns the maximum size of a response body that can be read by the client
+func (c *Client) responseLimit() int64 {
+	if c.maxResponseSize > 0 {
+		return c.maxResponseSize
+	}
+	return atomic.LoadInt64(&maxResponseSize)
+}
+
+//DoJSON makes a request with the json encoded in as the body and decodes the response's
+//Message envelope with its Data into out. in and out can be nil when there is no body to be sent or read.
+//It returns the message sent by the server. If the request fails or the server responds with a
+//non 2xx status, the error returned will be an *APIError
+func (c *Client) DoJSON(ctx context.Context, method, url string, in, out interface{}) (string, error) {
+	res, err := c.CallJSON(ctx, method, url, in, out)
+	if err != nil {
+		return "", err
+	}
+	return res.Message, nil
+}
+
+//Response has the details of a successful json request
+type Response struct {
+	//Message is the message sent by the server in the response envelope
+	Message string
+	//StatusCode is the http status of the response
+	StatusCode int
+	//Instance is the host:port of the service instance that served the request
+	Instance string
+	//IdempotencyKey is the idempotency key with which a mutating request was sent.
+	//The service detects the retries and failovers of the request as duplicates with it
+	IdempotencyKey string
+}
+
+//CallJSON makes the request like DoJSON and returns the details of the successful response along with the message
+func (c *Client) CallJSON(ctx context.Context, method, url string, in, out interface{}) (*Response, error) {
+	//making the request
+	res, apiErr, err := c.sendJSON(ctx, method, url, in)
+	if err != nil {
+		return nil, err
+	}
+	msg, err := c.decodeJSON(res, apiErr, out)
+	if err != nil {
+		return nil, err
+	}
+	return &Response{Message: msg, StatusCode: apiErr.StatusCode, Instance: apiErr.Instance, IdempotencyKey: apiErr.IdempotencyKey}, nil
+}
+
+//decodeJSON reads the response and decodes its Message envelope with the Data into out.
+//It returns the message sent by the server or the api error if the response is not a success
+func (c *Client) decodeJSON(res *http.Response, apiErr *APIError, out interface{}) (string, error) {
+	/*
+	 * First we will read the response
+	 * Then we will check the status of the response
+	 * Then we will decode the envelope into the data target
+	 */
+	defer res.Body.Close()
+
+	//reading the response
+	resBody, err := readBody(res.Body, c.responseLimit())
+	apiErr.Body = resBody
+	if err != nil {
+		apiErr.Err = err
+		return "", apiErr.failed()
+	}
+
+	//checking the status of the response
+	if err := statusError(apiErr); err != nil {
+		return "", err
+	}
+
+	//decoding the envelope
+	p := Message{Data: out}
+	err = json.Unmarshal(resBody, &p)
+	if err != nil {
+		apiErr.Err = err
+		return "", apiErr.failed()
+	}
+
+	return p.Message, nil
+}
+
+//StreamJSON makes a request like DoJSON but decodes the Data array of the response envelope as a stream
+//instead of buffering the whole response. fn is called for every element of the array with the decoder positioned
+//at the element and it has to decode the element with dec.Decode. Errors returned by fn stop the stream and are returned as such.
+//Streamed responses are not bounded by the maximum response size
+func (c *Client) StreamJSON(ctx context.Context, method, url string, in interface{}, fn func(dec *json.Decoder) error) (string, error) {
+	/*
+	 * First we will make the request
+	 * Then we will check the status of the response
+	 * Then we will walk through the envelope
+	 * Then we will stream the elements of the data
+	 */
+	//making the request
+	res, apiErr, err := c.sendJSON(ctx, method, url, in)
+	if err != nil {
+		return "", err
+	}
+	defer res.Body.Close()
+
+	//checking the status of the response
+	if res.StatusCode < http.StatusOK || res.StatusCode >= http.StatusMultipleChoices {
+		apiErr.Body, _ = readBody(res.Body, c.responseLimit())
+		return "", statusError(apiErr)
+	}
+
+	//walking through the envelope
+	msg := ""
+	dec := json.NewDecoder(res.Body)
+	if err := expectDelim(dec, '{'); err != nil {
+		apiErr.Err = err
+		return "", apiErr.failed()
+	}
+	for dec.More() {
+		t, err := dec.Token()
+		if err != nil {
+			apiErr.Err = err
+			return "", apiErr.failed()
+		}
+		key, _ := t.(string)
+		switch {
+		case strings.EqualFold(key, "Message"):
+			err = dec.Decode(&msg)
+		case strings.EqualFold(key, "Data"):
+			//streaming the elements of the data
+			err = streamArray(dec, fn)
+			if se, ok := err.(stopError); ok {
+				return "", se.err
+			}
+		default:
+			err = dec.Decode(&json.RawMessage{})
+		}
+		if err != nil {
+			apiErr.Err = err
+			return "", apiErr.failed()
+		}
+	}
+
+	return msg, nil
+}
+
+//stopError is the error returned by the callback of a stream
+type stopError struct {
+	err error
+}
+
+func (s stopError) Error() string {
+	return s.err.Error()
+}
+
+//streamArray calls fn for every element of the json array at the decoder.
+//The errors returned by fn are wrapped in stopError
+func streamArray(dec *json.Decoder, fn func(dec *json.Decoder) error) error {
+	t, err := dec.Token()
+	if err != nil {
+		return err
+	}
+	if t == nil {
+		//data is null
+		return nil
+	}
+	if d, ok := t.(json.Delim); !ok || d != '[' {
+		return fmt.Errorf("expected the data to be an array, got %v", t)
+	}
+	for dec.More() {
+		if err := fn(dec); err != nil {
+			return stopError{err: err}
+		}
+	}
+	return expectDelim(dec, ']')
+}
+
+//expectDelim reads the next token from the decoder and checks that it's the given delimiter
+func expectDelim(dec *json.Decoder, delim json.Delim) error {
+	t, err := dec.Token()
+	if err != nil {
+		return err
+	}
+	if d, ok := t.(json.Delim); !ok || d != delim {
+		return fmt.Errorf("expected %v in the response, got %v", delim, t)
+	}
+	return nil
+}
+
+//sendJSON makes the request with the json encoded in as the body. The api error returned along with the response
+//has the details of the request and the response status to be returned if the response turns out to be a failure
+func (c *Client) sendJSON(ctx context.Context, method, url string, in interface{}) (*http.Response, *APIError, error) {
+	//encoding the payload
+	var body io.Reader
+	if in != nil {
+		payload, err := json.Marshal(in)
+		if err != nil {
+			return nil, nil, err
+		}
+		body = bytes.NewReader(payload)
+	}
+
+	//making the request
+	req, err := http.NewRequest(method, url, body)
+	if err != nil {
+		return nil, nil, err
+	}
+	req = req.WithContext(ctx)
+	if in != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	return c.send(req, c.Do)
+}
+
+//send makes the request with do expecting a json response unless the request accepts others. The api error returned along with the response
+//has the details of the request and the response status
+func (c *Client) send(req *http.Request, do func(*http.Request) (*http.Response, error)) (*http.Response, *APIError, error) {
+	if req.Header.Get("Accept") == "" {
+		req.Header.Set("Accept", "application/json")
+	}
+	//the request has the headers and cookie set while sending it to be rendered as curl if it fails
+	apiErr := &APIError{URL: req.URL.String(), Instance: req.URL.Host, IdempotencyKey: setIdempotencyKey(req), req: req}
+	res, err := do(req)
+	if err != nil {
+		apiErr.Err = err
+		return nil, nil, apiErr.failed()
+	}
+	apiErr.StatusCode = res.StatusCode
+	return res, apiErr, nil
+}
+
+//readBody reads the body failing with ErrResponseTooLarge if it exceeds the limit
+func readBody(body io.Reader, limit int64) ([]byte, error) {
+	b, err := ioutil.ReadAll(io.LimitReader(body, limit+1))
+	if err != nil {
+		return b, err
+	}
+	if int64(len(b)) > limit {
+		return b[:limit], ErrResponseTooLarge
+	}
+	return b, nil
+}
+
+//statusError returns the api error with the message sent by the server if the status of the response is not 2xx
+func statusError(apiErr *APIError) error {
+	if apiErr.StatusCode >= http.StatusOK && apiErr.StatusCode < http.StatusMultipleChoices && apiErr.Err == nil {
+		return nil
+	}
+	p := Message{}
+	if json.Unmarshal(apiErr.Body, &p) == nil {
+		apiErr.Message = p.Message
+	}
+	if apiErr.Message == "" {
+		apiErr.Message = http.StatusText(apiErr.StatusCode)
+	}
+	return apiErr.failed()
+}
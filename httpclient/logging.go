@@ -0,0 +1,235 @@
+// Copyright 2019 Cuttle.ai. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package httpclient
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/cuttle-ai/brain/log"
+)
+
+//Redacted is the value with which the sensitive information is replaced in the logs
+const Redacted = "[REDACTED]"
+
+//DefaultSensitiveFields are the json fields and query params redacted by default.
+//It includes the credentials of the datastores
+var DefaultSensitiveFields = []string{"Password", "Username", "Token", "AccessToken", "Secret", "auth-token"}
+
+//sensitiveHeaders are the headers that are always redacted in the logs
+var sensitiveHeaders = []string{"Cookie", "Set-Cookie", "Authorization", "Proxy-Authorization"}
+
+//LogOptions are the options for the structured request logging
+type LogOptions struct {
+	//Headers enables logging the headers of the requests and responses
+	Headers bool
+	//Bodies enables logging the bodies of the requests and responses
+	Bodies bool
+	//Curl enables logging the curl command reproducing the request
+	Curl bool
+	//MaxBodySize is the maximum number of bytes of a body that is logged. Defaults to 4KB
+	MaxBodySize int
+	//SensitiveFields are the json fields and query params that are redacted, matched case insensitively.
+	//Defaults to DefaultSensitiveFields
+	SensitiveFields []string
+}
+
+//Logging returns the middleware that logs every attempt of the requests as a structured line with the
+//method, url, status, latency, bytes read and the retry number. Cookies, authorization headers and the sensitive fields
+//are redacted from the logs. The line is logged once the response body is closed
+func Logging(l log.Log, opts LogOptions) Middleware {
+	if opts.MaxBodySize <= 0 {
+		opts.MaxBodySize = 4 << 10
+	}
+	if opts.SensitiveFields == nil {
+		opts.SensitiveFields = DefaultSensitiveFields
+	}
+	r := redactor{fields: map[string]bool{}}
+	for _, f := range opts.SensitiveFields {
+		r.fields[strings.ToLower(f)] = true
+	}
+
+	return func(next http.RoundTripper) http.RoundTripper {
+		return RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			entry := &logEntry{l: RequestLogger(req.Context(), l), start: time.Now()}
+			entry.add("method", req.Method)
+			entry.add("url", r.url(req.URL))
+			entry.add("attempt", strconv.Itoa(currentAttempt(req.Context())))
+			if opts.Headers {
+				entry.add("request_headers", r.headers(req.Header))
+			}
+			if opts.Curl {
+				entry.add("curl", r.curl(req))
+			}
+			if opts.Bodies && req.GetBody != nil {
+				if body, err := req.GetBody(); err == nil {
+					b, _ := ioutil.ReadAll(io.LimitReader(body, int64(opts.MaxBodySize)))
+					body.Close()
+					entry.add("request_body", r.body(b))
+				}
+			}
+
+			res, err := next.RoundTrip(req)
+			if err != nil {
+				entry.failed = true
+				entry.add("error", err.Error())
+				entry.log()
+				return nil, err
+			}
+			entry.add("status", strconv.Itoa(res.StatusCode))
+			if opts.Headers {
+				entry.add("response_headers", r.headers(res.Header))
+			}
+			lb := &loggingBody{ReadCloser: res.Body, entry: entry, r: r}
+			if opts.Bodies {
+				lb.capture = &bytes.Buffer{}
+				lb.max = opts.MaxBodySize
+			}
+			res.Body = lb
+			return res, nil
+		})
+	}
+}
+
+//logEntry is the structured log line of a request
+type logEntry struct {
+	l      log.Log
+	start  time.Time
+	fields []string
+	failed bool
+}
+
+//add adds the key value pair to the log line
+func (e *logEntry) add(key, value string) {
+	e.fields = append(e.fields, key+"="+strconv.Quote(value))
+}
+
+//log logs the line with the latency of the request
+func (e *logEntry) log() {
+	e.add("latency", time.Since(e.start).String())
+	line := "sdk request " + strings.Join(e.fields, " ")
+	if e.failed {
+		e.l.Error(line)
+		return
+	}
+	e.l.Info(line)
+}
+
+//loggingBody counts the bytes read from the response body and logs the entry once closed
+type loggingBody struct {
+	io.ReadCloser
+	entry   *logEntry
+	r       redactor
+	n       int
+	capture *bytes.Buffer
+	max     int
+	closed  bool
+}
+
+//Read reads from the response body counting and capturing the bytes read
+func (b *loggingBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	b.n += n
+	if b.capture != nil && b.capture.Len() < b.max {
+		rem := b.max - b.capture.Len()
+		if rem > n {
+			rem = n
+		}
+		b.capture.Write(p[:rem])
+	}
+	return n, err
+}
+
+//Close closes the response body and logs the entry
+func (b *loggingBody) Close() error {
+	err := b.ReadCloser.Close()
+	if b.closed {
+		return err
+	}
+	b.closed = true
+	b.entry.add("bytes", strconv.Itoa(b.n))
+	if b.capture != nil {
+		b.entry.add("response_body", b.r.body(b.capture.Bytes()))
+	}
+	b.entry.log()
+	return err
+}
+
+//redactor redacts the sensitive information from the logged values
+type redactor struct {
+	fields map[string]bool
+}
+
+//url returns the url with the password and sensitive query params redacted
+func (r redactor) url(u *url.URL) string {
+	c := *u
+	if c.User != nil {
+		if _, ok := c.User.Password(); ok {
+			c.User = url.UserPassword(c.User.Username(), Redacted)
+		}
+	}
+	q := c.Query()
+	for k := range q {
+		if r.fields[strings.ToLower(k)] {
+			q.Set(k, Redacted)
+		}
+	}
+	if len(q) > 0 {
+		c.RawQuery = q.Encode()
+	}
+	return c.String()
+}
+
+//headers returns the headers with the cookies and authorization redacted
+func (r redactor) headers(h http.Header) string {
+	c := h.Clone()
+	for _, k := range sensitiveHeaders {
+		if _, ok := c[k]; ok {
+			c.Set(k, Redacted)
+		}
+	}
+	return fmt.Sprint(c)
+}
+
+//body returns the body with the sensitive json fields redacted. Non json bodies are not logged
+func (r redactor) body(b []byte) string {
+	if len(b) == 0 {
+		return ""
+	}
+	var v interface{}
+	if err := json.Unmarshal(b, &v); err != nil {
+		return "<" + strconv.Itoa(len(b)) + " bytes of non json or truncated body>"
+	}
+	v = r.redact(v)
+	res, _ := json.Marshal(v)
+	return string(res)
+}
+
+//redact replaces the values of the sensitive fields in the decoded json
+func (r redactor) redact(v interface{}) interface{} {
+	switch t := v.(type) {
+	case map[string]interface{}:
+		for k, val := range t {
+			if r.fields[strings.ToLower(k)] {
+				t[k] = Redacted
+				continue
+			}
+			t[k] = r.redact(val)
+		}
+	case []interface{}:
+		for i, val := range t {
+			t[i] = r.redact(val)
+		}
+	}
+	return v
+}
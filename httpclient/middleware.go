@@ -0,0 +1,65 @@
+// Copyright 2019 Cuttle.ai. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package httpclient
+
+import (
+	"net/http"
+	"sync"
+)
+
+//RoundTripperFunc is an adapter to use ordinary functions as http.RoundTripper
+type RoundTripperFunc func(*http.Request) (*http.Response, error)
+
+//RoundTrip calls f(req)
+func (f RoundTripperFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+//Middleware wraps the next round tripper in the chain to intercept the requests made by the sdk.
+//Each attempt of a request including the retries goes through the chain. Middlewares must not modify
+//the request given to them, they have to clone it before changing it as per the http.RoundTripper contract
+type Middleware func(next http.RoundTripper) http.RoundTripper
+
+var (
+	middlewaresMu sync.RWMutex
+	middlewares   []Middleware
+)
+
+//Use registers the middlewares that will see every request made through the httpclient package
+//including the ones made by the service packages of the sdk.
+//Middlewares registered first will be the outermost in the chain
+func Use(mws ...Middleware) {
+	middlewaresMu.Lock()
+	middlewares = append(middlewares, mws...)
+	middlewaresMu.Unlock()
+}
+
+//Use registers the middlewares that will see only the requests made by the client.
+//They are applied inside the middlewares registered with the package level Use
+func (c *Client) Use(mws ...Middleware) {
+	c.middlewares = append(c.middlewares, mws...)
+}
+
+//chain returns the round tripper with the given middlewares applied over the base round tripper
+func chain(base http.RoundTripper, mws ...Middleware) http.RoundTripper {
+	rt := base
+	for i := len(mws) - 1; i >= 0; i-- {
+		rt = mws[i](rt)
+	}
+	return rt
+}
+
+//transport returns the round tripper with the package level and client's middlewares applied.
+//Every attempt is traced, measured and given its share of the deadline before going through the middlewares and compressed after them
+func (c *Client) transport() http.RoundTripper {
+	middlewaresMu.RLock()
+	mws := make([]Middleware, 0, len(middlewares)+len(c.middlewares)+3)
+	mws = append(mws, instrumentAttempt, budget)
+	mws = append(mws, middlewares...)
+	middlewaresMu.RUnlock()
+	mws = append(mws, c.middlewares...)
+	mws = append(mws, compression)
+	return chain(getTransport(), mws...)
+}
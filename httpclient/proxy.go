@@ -0,0 +1,119 @@
+// Copyright 2019 Cuttle.ai. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package httpclient
+
+import (
+	"errors"
+	"net/http"
+	"net/url"
+	"sync"
+
+	"golang.org/x/net/http/httpproxy"
+)
+
+//ProxyOptions are the options for the outbound proxy through which the calls are made
+type ProxyOptions struct {
+	//URL is the url of the proxy with the http, https or socks5 scheme. The credentials of the proxy can be given in the url.
+	//The proxy in the HTTP_PROXY, HTTPS_PROXY and NO_PROXY environment variables is used if it's empty
+	URL string
+	//NoProxy is the comma separated list of the hosts, domains, ips and cidrs reached without the proxy like in NO_PROXY.
+	//It overrides the NO_PROXY environment variable
+	NoProxy string
+	//Direct makes the calls without any proxy, ignoring the environment
+	Direct bool
+}
+
+//proxyFunc returns the proxy of the request
+type proxyFunc func(*http.Request) (*url.URL, error)
+
+var (
+	proxyMu sync.RWMutex
+	//globalProxy is the proxy of the calls to the services without a proxy of their own
+	globalProxy proxyFunc = http.ProxyFromEnvironment
+	//serviceProxies are the proxies of the services overriding the global one
+	serviceProxies = map[string]proxyFunc{}
+)
+
+//ConfigureProxy sets the outbound proxy of the calls made to the platform services. The services can override it
+//with SetServiceProxy. By default the proxy in the environment variables is used.
+//The instances reached on unix sockets or over h2c are always called directly
+func ConfigureProxy(opts ProxyOptions) error {
+	p, err := opts.proxyFunc()
+	if err != nil {
+		return err
+	}
+	proxyMu.Lock()
+	globalProxy = p
+	proxyMu.Unlock()
+	return nil
+}
+
+//SetServiceProxy sets the outbound proxy of the calls made to the platform service overriding the global one
+func SetServiceProxy(service string, opts ProxyOptions) error {
+	p, err := opts.proxyFunc()
+	if err != nil {
+		return err
+	}
+	proxyMu.Lock()
+	serviceProxies[service] = p
+	proxyMu.Unlock()
+	return nil
+}
+
+//RemoveServiceProxy removes the proxy set for the platform service so that its calls go through the global one
+func RemoveServiceProxy(service string) {
+	proxyMu.Lock()
+	delete(serviceProxies, service)
+	proxyMu.Unlock()
+}
+
+//ServiceProxy returns the function giving the proxy of the requests made to the platform service.
+//It can be set as the Proxy of the transports not built by the sdk, like the one of the discovery client
+func ServiceProxy(service string) func(*http.Request) (*url.URL, error) {
+	return func(req *http.Request) (*url.URL, error) {
+		proxyMu.RLock()
+		p, ok := serviceProxies[service]
+		if !ok {
+			p = globalProxy
+		}
+		proxyMu.RUnlock()
+		return p(req)
+	}
+}
+
+//proxy returns the proxy of the request as per the service in its context.
+//The requests to the instances served on unix sockets aren't proxied
+func proxy(req *http.Request) (*url.URL, error) {
+	if getRoute(req.URL.Host).socket != "" {
+		return nil, nil
+	}
+	service, _ := Operation(req.Context())
+	return ServiceProxy(service)(req)
+}
+
+//proxyFunc validates the options and returns the proxy function for them
+func (o ProxyOptions) proxyFunc() (proxyFunc, error) {
+	if o.Direct {
+		return func(*http.Request) (*url.URL, error) { return nil, nil }, nil
+	}
+	config := httpproxy.FromEnvironment()
+	if o.URL != "" {
+		u, err := url.Parse(o.URL)
+		if err != nil {
+			return nil, err
+		}
+		if u.Scheme != "http" && u.Scheme != "https" && u.Scheme != "socks5" {
+			return nil, errors.New("unsupported scheme of the proxy " + u.Scheme)
+		}
+		config.HTTPProxy, config.HTTPSProxy = o.URL, o.URL
+	}
+	if o.NoProxy != "" {
+		config.NoProxy = o.NoProxy
+	}
+	p := config.ProxyFunc()
+	return func(req *http.Request) (*url.URL, error) {
+		return p(req.URL)
+	}, nil
+}
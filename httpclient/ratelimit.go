@@ -0,0 +1,141 @@
+// Copyright 2019 Cuttle.ai. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package httpclient
+
+import (
+	"context"
+	"errors"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/cuttle-ai/go-sdk/metrics"
+)
+
+//RateLimit is the token bucket limit for the calls made to a platform service
+type RateLimit struct {
+	//Rate is the number of calls allowed per second
+	Rate float64
+	//Burst is the number of calls that can be made at once. Defaults to 1
+	Burst int
+	//FailFast makes the throttled calls fail with ErrRateLimited instead of waiting for their turn
+	FailFast bool
+}
+
+//bucket is the token bucket of a rate limit
+type bucket struct {
+	mu     sync.Mutex
+	limit  RateLimit
+	tokens float64
+	last   time.Time
+}
+
+//reserve takes a token from the bucket and returns the time to wait for it.
+//If the bucket fails fast and there is no token available, it returns false without taking the token
+func (b *bucket) reserve(now time.Time) (time.Duration, bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.tokens = math.Min(float64(b.limit.Burst), b.tokens+now.Sub(b.last).Seconds()*b.limit.Rate)
+	b.last = now
+	if b.tokens >= 1 {
+		b.tokens--
+		return 0, true
+	}
+	if b.limit.FailFast {
+		return 0, false
+	}
+	wait := time.Duration((1 - b.tokens) / b.limit.Rate * float64(time.Second))
+	b.tokens--
+	return wait, true
+}
+
+//cancel returns the token taken by a reservation that wasn't used
+func (b *bucket) cancel() {
+	b.mu.Lock()
+	b.tokens = math.Min(float64(b.limit.Burst), b.tokens+1)
+	b.mu.Unlock()
+}
+
+var (
+	rateLimitsMu sync.RWMutex
+	rateLimits   = map[string]*bucket{}
+)
+
+//rateLimitKey returns the key of the rate limit of a service's operation
+func rateLimitKey(service, operation string) string {
+	return service + "/" + operation
+}
+
+//SetRateLimit sets the rate limit for the calls made to the platform service for the sdk operation like
+//datastores.GetDatastore. The operation can be empty to limit all the calls made to the service.
+//When both are set, a call has to be allowed by both of them. The rate should be a finite number greater than 0
+func SetRateLimit(service, operation string, limit RateLimit) error {
+	if !(limit.Rate > 0) || math.IsInf(limit.Rate, 1) {
+		return errors.New("rate of the limit should be a finite number greater than 0")
+	}
+	if limit.Burst <= 0 {
+		limit.Burst = 1
+	}
+	rateLimitsMu.Lock()
+	rateLimits[rateLimitKey(service, operation)] = &bucket{limit: limit, tokens: float64(limit.Burst), last: time.Now()}
+	rateLimitsMu.Unlock()
+	return nil
+}
+
+//RemoveRateLimit removes the rate limit set for the platform service and sdk operation
+func RemoveRateLimit(service, operation string) {
+	rateLimitsMu.Lock()
+	delete(rateLimits, rateLimitKey(service, operation))
+	rateLimitsMu.Unlock()
+}
+
+//waitRateLimit waits till the rate limits of the operation in the context allow the call.
+//It fails with ErrRateLimited if a fail fast limit doesn't allow the call or with the context's error if it is done while waiting
+func waitRateLimit(ctx context.Context) error {
+	service, operation := Operation(ctx)
+	rateLimitsMu.RLock()
+	buckets := []*bucket{}
+	if b, ok := rateLimits[rateLimitKey(service, "")]; ok {
+		buckets = append(buckets, b)
+	}
+	if b, ok := rateLimits[rateLimitKey(service, operation)]; ok && operation != "" {
+		buckets = append(buckets, b)
+	}
+	rateLimitsMu.RUnlock()
+
+	//reserving the tokens in all the buckets
+	now := time.Now()
+	wait := time.Duration(0)
+	for i, b := range buckets {
+		w, ok := b.reserve(now)
+		if !ok {
+			for _, r := range buckets[:i] {
+				r.cancel()
+			}
+			metrics.ObserveThrottled(service, operation, true)
+			return ErrRateLimited
+		}
+		if w > wait {
+			wait = w
+		}
+	}
+	if wait == 0 {
+		return nil
+	}
+
+	//waiting for the turn
+	metrics.ObserveThrottled(service, operation, false)
+	timer := time.NewTimer(wait)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		for _, b := range buckets {
+			b.cancel()
+		}
+		return ctx.Err()
+	}
+}
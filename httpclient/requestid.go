@@ -0,0 +1,146 @@
+// Copyright 2019 Cuttle.ai. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package httpclient
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"net/http"
+	"strings"
+
+	"github.com/cuttle-ai/brain/log"
+)
+
+const (
+	//RequestIDHeader is the header in which the request id is sent to the platform services
+	RequestIDHeader = "X-Request-ID"
+	//TraceParentHeader is the header in which the w3c trace context is sent to the platform services
+	TraceParentHeader = "traceparent"
+)
+
+type requestIDKey struct{}
+
+type traceParentKey struct{}
+
+//WithRequestID returns a copy of the context carrying the request id
+func WithRequestID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, requestIDKey{}, id)
+}
+
+//RequestID returns the request id carried by the context. It will be empty if the context has none
+func RequestID(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDKey{}).(string)
+	return id
+}
+
+//WithTraceParent returns a copy of the context carrying the w3c traceparent
+func WithTraceParent(ctx context.Context, traceParent string) context.Context {
+	return context.WithValue(ctx, traceParentKey{}, traceParent)
+}
+
+//TraceParent returns the w3c traceparent carried by the context. It will be empty if the context has none
+func TraceParent(ctx context.Context) string {
+	tp, _ := ctx.Value(traceParentKey{}).(string)
+	return tp
+}
+
+//FromRequest returns a copy of the context carrying the request id and traceparent of an incoming request
+//so that they can be propagated to the platform services called while serving it
+func FromRequest(ctx context.Context, r *http.Request) context.Context {
+	if id := r.Header.Get(RequestIDHeader); id != "" {
+		ctx = WithRequestID(ctx, id)
+	}
+	if tp := r.Header.Get(TraceParentHeader); validTraceParent(tp) {
+		ctx = WithTraceParent(ctx, tp)
+	}
+	return ctx
+}
+
+//EnsureRequestContext returns a copy of the context with a request id and traceparent.
+//The ones missing in the context are generated
+func EnsureRequestContext(ctx context.Context) context.Context {
+	tp := TraceParent(ctx)
+	if !validTraceParent(tp) {
+		tp = "00-" + randomHex(16) + "-" + randomHex(8) + "-01"
+		ctx = WithTraceParent(ctx, tp)
+	}
+	if RequestID(ctx) == "" {
+		//the trace id is unique for the request, so we reuse it as the request id
+		ctx = WithRequestID(ctx, strings.Split(tp, "-")[1])
+	}
+	return ctx
+}
+
+//setRequestHeaders sets the request id and traceparent headers from the request's context
+func setRequestHeaders(req *http.Request) {
+	ctx := req.Context()
+	if id := RequestID(ctx); id != "" && req.Header.Get(RequestIDHeader) == "" {
+		req.Header.Set(RequestIDHeader, id)
+	}
+	if tp := TraceParent(ctx); tp != "" && req.Header.Get(TraceParentHeader) == "" {
+		req.Header.Set(TraceParentHeader, tp)
+	}
+}
+
+//validTraceParent checks whether the traceparent is in the version 00 format of w3c trace context
+func validTraceParent(tp string) bool {
+	parts := strings.Split(tp, "-")
+	if len(parts) != 4 || parts[0] != "00" || len(parts[1]) != 32 || len(parts[2]) != 16 || len(parts[3]) != 2 {
+		return false
+	}
+	for _, p := range parts[1:] {
+		if _, err := hex.DecodeString(p); err != nil {
+			return false
+		}
+	}
+	return parts[1] != strings.Repeat("0", 32) && parts[2] != strings.Repeat("0", 16)
+}
+
+//randomHex returns n random bytes encoded as hex
+func randomHex(n int) string {
+	b := make([]byte, n)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+//requestLogger prefixes the log lines of every level with the request id
+type requestLogger struct {
+	log.Log
+	prefix string
+}
+
+//Info logs the information with the request id
+func (r requestLogger) Info(v ...interface{}) {
+	r.Log.Info(append([]interface{}{r.prefix}, v...)...)
+}
+
+//Error logs the error with the request id
+func (r requestLogger) Error(v ...interface{}) {
+	r.Log.Error(append([]interface{}{r.prefix}, v...)...)
+}
+
+//Warn logs the warning with the request id
+func (r requestLogger) Warn(v ...interface{}) {
+	r.Log.Warn(append([]interface{}{r.prefix}, v...)...)
+}
+
+//Debug logs the debug information with the request id
+func (r requestLogger) Debug(v ...interface{}) {
+	r.Log.Debug(append([]interface{}{r.prefix}, v...)...)
+}
+
+//RequestLogger returns the logger that includes the request id carried by the context in the log lines.
+//If the context has no request id, the logger is returned as such
+func RequestLogger(ctx context.Context, l log.Log) log.Log {
+	if rl, ok := l.(requestLogger); ok {
+		l = rl.Log
+	}
+	id := RequestID(ctx)
+	if id == "" {
+		return l
+	}
+	return requestLogger{Log: l, prefix: "request-id=" + id}
+}
@@ -0,0 +1,109 @@
+// Copyright 2019 Cuttle.ai. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package httpclient
+
+import (
+	"context"
+	"crypto/tls"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/net/http2"
+)
+
+const (
+	//MetaUnixSocket is the discovery metadata of an instance with the path of the unix socket on which it's served.
+	//It is used by the sidecars running on the same host as the application
+	MetaUnixSocket = "unix-socket"
+	//MetaProtocol is the discovery metadata of an instance with the protocol with which it's served.
+	//Instances with ProtocolH2C are called over http2 without tls
+	MetaProtocol = "protocol"
+	//ProtocolH2C is the value of the MetaProtocol of the instances served over http2 cleartext
+	ProtocolH2C = "h2c"
+)
+
+//route is how an instance of a platform service is reached
+type route struct {
+	socket string
+	h2c    bool
+}
+
+var (
+	routesMu sync.RWMutex
+	routes   = map[string]route{}
+)
+
+//RouteInstance sets how the instance at the address and port is reached from its discovery metadata.
+//Connections to the instance are dialed on the MetaUnixSocket if present and made over h2c if the MetaProtocol is ProtocolH2C.
+//The urls of the instance stay the same. Discovery routes every instance it finds
+func RouteInstance(address string, port int, meta map[string]string) {
+	host := net.JoinHostPort(address, strconv.Itoa(port))
+	r := route{socket: meta[MetaUnixSocket], h2c: strings.EqualFold(meta[MetaProtocol], ProtocolH2C)}
+	routesMu.Lock()
+	if r == (route{}) {
+		delete(routes, host)
+	} else {
+		routes[host] = r
+	}
+	routesMu.Unlock()
+}
+
+//getRoute returns the route of the instance at the host
+func getRoute(host string) route {
+	routesMu.RLock()
+	defer routesMu.RUnlock()
+	return routes[host]
+}
+
+//routingTransport sends the requests to the h2c instances over http2 cleartext and the others over the http transport
+type routingTransport struct {
+	http *http.Transport
+	h2c  *http2.Transport
+}
+
+//newRoutingTransport returns the transport reaching the instances as per their routes. The http transport is used for
+//the h2c instances as well when tls is configured since http2 is negotiated over tls
+func newRoutingTransport(t *http.Transport) *routingTransport {
+	d := &net.Dialer{Timeout: 30 * time.Second, KeepAlive: 30 * time.Second}
+	dial := func(ctx context.Context, network, addr string) (net.Conn, error) {
+		if r := getRoute(addr); r.socket != "" {
+			return d.DialContext(ctx, "unix", r.socket)
+		}
+		return d.DialContext(ctx, network, addr)
+	}
+	t.DialContext = dial
+	return &routingTransport{
+		http: t,
+		h2c: &http2.Transport{
+			AllowHTTP: true,
+			DialTLS: func(network, addr string, _ *tls.Config) (net.Conn, error) {
+				return dial(context.Background(), network, addr)
+			},
+		},
+	}
+}
+
+//RoundTrip sends the request over the transport of the instance's route.
+//The h2c connections are multiplexed and kept open even if the request asks to close its connection like the retried ones do
+func (r *routingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.URL.Scheme == "http" && getRoute(req.URL.Host).h2c {
+		if req.Close {
+			req = req.Clone(req.Context())
+			req.Close = false
+		}
+		return r.h2c.RoundTrip(req)
+	}
+	return r.http.RoundTrip(req)
+}
+
+//CloseIdleConnections closes the idle connections of the transports
+func (r *routingTransport) CloseIdleConnections() {
+	r.http.CloseIdleConnections()
+	r.h2c.CloseIdleConnections()
+}
@@ -0,0 +1,168 @@
+// Copyright 2019 Cuttle.ai. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package httpclient
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"io/ioutil"
+	"os"
+	"time"
+)
+
+//DefaultTLSReloadInterval is the interval at which the certificate files are checked for changes by default
+const DefaultTLSReloadInterval = time.Minute
+
+//TLSOptions are the options for the tls used in the calls to the platform services
+type TLSOptions struct {
+	//CAFile is the PEM bundle of the certificate authorities to verify the services with.
+	//The system's pool is used if empty
+	CAFile string
+	//CertFile is the PEM certificate presented to the services for mutual tls. KeyFile is required along with it
+	CertFile string
+	//KeyFile is the PEM key of the client certificate
+	KeyFile string
+	//MinVersion is the minimum tls version accepted. Defaults to tls 1.2
+	MinVersion uint16
+	//ServerName overrides the name with which the certificates of the services are verified.
+	//It is required when the services are discovered with their ip addresses
+	ServerName string
+	//ReloadInterval is the interval at which the files are checked for changes and reloaded.
+	//Defaults to DefaultTLSReloadInterval. A negative interval disables the reload
+	ReloadInterval time.Duration
+	//OnReloadError is called with the error when the changed files couldn't be reloaded. The previous config stays in use
+	OnReloadError func(error)
+}
+
+var stopTLSReload chan struct{}
+
+//ConfigureTLS makes the calls to the platform services over https with the tls options.
+//The certificate files are hot reloaded when they change
+func ConfigureTLS(opts TLSOptions) error {
+	/*
+	 * First we will load the tls config
+	 * Then we will rebuild the transport with it
+	 * Then we will start watching the files for changes
+	 */
+	//loading the config
+	if (opts.CertFile == "") != (opts.KeyFile == "") {
+		return errors.New("both the cert file and key file are required for mutual tls")
+	}
+	config, err := loadTLSConfig(opts)
+	if err != nil {
+		return err
+	}
+
+	//rebuilding the transport
+	transportMu.Lock()
+	tlsConfig = config
+	rebuildTransport()
+	if stopTLSReload != nil {
+		close(stopTLSReload)
+		stopTLSReload = nil
+	}
+	if opts.ReloadInterval == 0 {
+		opts.ReloadInterval = DefaultTLSReloadInterval
+	}
+	if opts.ReloadInterval > 0 {
+		stopTLSReload = make(chan struct{})
+		go watchTLSFiles(opts, stopTLSReload)
+	}
+	transportMu.Unlock()
+	return nil
+}
+
+//DisableTLS makes the calls to the platform services over plain http and stops reloading the certificate files
+func DisableTLS() {
+	transportMu.Lock()
+	tlsConfig = nil
+	rebuildTransport()
+	if stopTLSReload != nil {
+		close(stopTLSReload)
+		stopTLSReload = nil
+	}
+	transportMu.Unlock()
+}
+
+//loadTLSConfig creates the tls config by reading the files in the options
+func loadTLSConfig(opts TLSOptions) (*tls.Config, error) {
+	config := &tls.Config{
+		MinVersion: opts.MinVersion,
+		ServerName: opts.ServerName,
+	}
+	if config.MinVersion == 0 {
+		config.MinVersion = tls.VersionTLS12
+	}
+	if opts.CAFile != "" {
+		ca, err := ioutil.ReadFile(opts.CAFile)
+		if err != nil {
+			return nil, err
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(ca) {
+			return nil, errors.New("no certificates could be parsed from the ca file " + opts.CAFile)
+		}
+		config.RootCAs = pool
+	}
+	if opts.CertFile != "" {
+		cert, err := tls.LoadX509KeyPair(opts.CertFile, opts.KeyFile)
+		if err != nil {
+			return nil, err
+		}
+		config.Certificates = []tls.Certificate{cert}
+	}
+	return config, nil
+}
+
+//watchTLSFiles reloads the tls config whenever any of the files in the options change till stop is closed
+func watchTLSFiles(opts TLSOptions, stop chan struct{}) {
+	ticker := time.NewTicker(opts.ReloadInterval)
+	defer ticker.Stop()
+	last := modTimes(opts)
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+		}
+		current := modTimes(opts)
+		if current == last {
+			continue
+		}
+		config, err := loadTLSConfig(opts)
+		if err != nil {
+			//the files may be in the middle of being replaced, so we will retry in the next tick
+			if opts.OnReloadError != nil {
+				opts.OnReloadError(err)
+			}
+			continue
+		}
+		last = current
+		transportMu.Lock()
+		select {
+		case <-stop:
+			//tls was reconfigured while we were reloading
+		default:
+			tlsConfig = config
+			rebuildTransport()
+		}
+		transportMu.Unlock()
+	}
+}
+
+//modTimes returns the modification times of the files in the options
+func modTimes(opts TLSOptions) [3]time.Time {
+	times := [3]time.Time{}
+	for i, f := range []string{opts.CAFile, opts.CertFile, opts.KeyFile} {
+		if f == "" {
+			continue
+		}
+		if info, err := os.Stat(f); err == nil {
+			times[i] = info.ModTime()
+		}
+	}
+	return times
+}
@@ -0,0 +1,63 @@
+// Copyright 2019 Cuttle.ai. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package httpclient
+
+import (
+	"crypto/tls"
+	"net/http"
+	"strconv"
+	"sync"
+)
+
+var (
+	transportMu sync.RWMutex
+	//tlsConfig is the tls config used for the calls to the platform services. Calls are made over plain http if nil
+	tlsConfig *tls.Config
+	//baseTransport is the transport built from the settings over which the middlewares are applied
+	baseTransport http.RoundTripper = buildTransport()
+)
+
+//buildTransport returns the base transport for the current settings. The proxy of the requests is
+//resolved as per their service when they are sent. It has to be called with the transportMu locked
+func buildTransport() http.RoundTripper {
+	t := http.DefaultTransport.(*http.Transport).Clone()
+	t.Proxy = proxy
+	if tlsConfig != nil {
+		t.TLSClientConfig = tlsConfig.Clone()
+	}
+	return newRoutingTransport(t)
+}
+
+//rebuildTransport builds the base transport from the current settings.
+//It has to be called with the transportMu locked
+func rebuildTransport() {
+	old := baseTransport
+	baseTransport = buildTransport()
+	if o, ok := old.(interface{ CloseIdleConnections() }); ok {
+		o.CloseIdleConnections()
+	}
+}
+
+//getTransport returns the base transport
+func getTransport() http.RoundTripper {
+	transportMu.RLock()
+	defer transportMu.RUnlock()
+	return baseTransport
+}
+
+//Scheme returns the scheme with which the platform services are called. It is https if tls is configured
+func Scheme() string {
+	transportMu.RLock()
+	defer transportMu.RUnlock()
+	if tlsConfig != nil {
+		return "https"
+	}
+	return "http"
+}
+
+//InstanceURL returns the url of the path in the instance of a platform service at the address and port
+func InstanceURL(address string, port int, path string) string {
+	return Scheme() + "://" + address + ":" + strconv.Itoa(port) + path
+}
@@ -0,0 +1,248 @@
+// Copyright 2019 Cuttle.ai. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package httpclient
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/textproto"
+	"strconv"
+	"strings"
+)
+
+//DefaultChunkSize is the size of the chunks of a chunked upload by default
+const DefaultChunkSize = 8 << 20
+
+//UploadIDHeader is the header in which the id of a chunked upload is sent with each of its chunks
+const UploadIDHeader = "X-Upload-ID"
+
+//UploadOffsetHeader is the header in which the services send the number of bytes of a chunked upload they have received
+const UploadOffsetHeader = "Upload-Offset"
+
+//ProgressFunc is called with the number of bytes sent so far while uploading
+type ProgressFunc func(sent int64)
+
+//Part is a part of a multipart/form-data upload
+type Part struct {
+	//Name is the name of the form field
+	Name string
+	//FileName is the name of the uploaded file. Parts without it are sent as plain form fields
+	FileName string
+	//ContentType is the content type of the part. Defaults to application/octet-stream for the files
+	ContentType string
+	//Content is read while the part is being sent
+	Content io.Reader
+}
+
+//Upload posts the parts as a multipart/form-data body and decodes the response's Message envelope with its Data into out.
+//The body is streamed while it's being read from the parts without buffering it, so the request is not retried.
+//progress if not nil is called with the bytes of the body sent so far, including the multipart boundaries
+func (c *Client) Upload(ctx context.Context, url string, parts []Part, progress ProgressFunc, out interface{}) (string, error) {
+	/*
+	 * First we will start writing the parts to a pipe
+	 * Then we will make the request with the pipe as the body
+	 * Then we will decode the response
+	 */
+	//writing the parts to the pipe
+	pr, pw := io.Pipe()
+	defer pr.Close()
+	mw := multipart.NewWriter(pw)
+	go func() {
+		pw.CloseWithError(writeParts(mw, parts))
+	}()
+
+	//making the request
+	req, err := http.NewRequest(http.MethodPost, url, &progressReader{r: pr, fn: progress})
+	if err != nil {
+		return "", err
+	}
+	req = req.WithContext(ctx)
+	req.Header.Set("Content-Type", mw.FormDataContentType())
+	res, apiErr, err := c.send(req, c.doOnce)
+	if err != nil {
+		return "", err
+	}
+
+	//decoding the response
+	return c.decodeJSON(res, apiErr, out)
+}
+
+//writeParts writes the parts to the multipart writer and closes it
+func writeParts(mw *multipart.Writer, parts []Part) error {
+	quote := strings.NewReplacer("\\", "\\\\", `"`, "\\\"")
+	for _, p := range parts {
+		h := textproto.MIMEHeader{}
+		disposition := `form-data; name="` + quote.Replace(p.Name) + `"`
+		if p.FileName != "" {
+			disposition += `; filename="` + quote.Replace(p.FileName) + `"`
+			if p.ContentType == "" {
+				p.ContentType = "application/octet-stream"
+			}
+		}
+		h.Set("Content-Disposition", disposition)
+		if p.ContentType != "" {
+			h.Set("Content-Type", p.ContentType)
+		}
+		w, err := mw.CreatePart(h)
+		if err != nil {
+			return err
+		}
+		if p.Content == nil {
+			continue
+		}
+		if _, err := io.Copy(w, p.Content); err != nil {
+			return fmt.Errorf("error while reading the part %s: %w", p.Name, err)
+		}
+	}
+	return mw.Close()
+}
+
+//progressReader reports the bytes read from the reader
+type progressReader struct {
+	r    io.Reader
+	fn   ProgressFunc
+	sent int64
+}
+
+//Read reads from the reader reporting the progress
+func (p *progressReader) Read(b []byte) (int, error) {
+	n, err := p.r.Read(b)
+	if n > 0 && p.fn != nil {
+		p.sent += int64(n)
+		p.fn(p.sent)
+	}
+	return n, err
+}
+
+//ChunkedUpload is the state of a resumable upload sent in chunks. Each chunk is sent with its Content-Range and
+//the UploadIDHeader so that the service can assemble them. A failed upload can be resumed by uploading
+//the same content again with the same ID, even from another process, which continues from the offset received by the service
+type ChunkedUpload struct {
+	//URL is the url to which the chunks are sent
+	URL string
+	//Method is the http method with which the chunks are sent. Defaults to PUT
+	Method string
+	//ID identifies the upload across its chunks and resumptions. It is generated if empty
+	ID string
+	//ContentType is the content type of the uploaded content. Defaults to application/octet-stream
+	ContentType string
+	//ChunkSize is the size of the chunks. Defaults to DefaultChunkSize
+	ChunkSize int64
+	//Offset is the number of bytes already uploaded. It is replaced by the offset received by the service when resuming
+	Offset int64
+	//Progress if not nil is called with the bytes of the content uploaded so far
+	Progress ProgressFunc
+}
+
+//UploadChunks uploads the size bytes of the content from the upload's offset in chunks, each of them being retried on failures.
+//The Message envelope of the last chunk's response is decoded with its Data into out.
+//The offset of the upload is advanced as the chunks are uploaded. Every chunk is sent with an idempotency key
+//derived from the upload id and its offset, so that a resumed chunk isn't assembled twice.
+//Content shorter than the size fails with io.ErrUnexpectedEOF before its last chunk is sent.
+//An upload with an ID is resumed from the UploadOffsetHeader sent by the service for a HEAD request with the UploadIDHeader.
+//If the service doesn't send it, the upload continues from its Offset, which is right only within the process that got
+//the error response of the failed chunk
+func (c *Client) UploadChunks(ctx context.Context, u *ChunkedUpload, content io.ReaderAt, size int64, out interface{}) (string, error) {
+	if size <= 0 {
+		return "", errors.New("there is no content to be uploaded")
+	}
+	if u.ID == "" {
+		u.ID = newIdempotencyKey()
+	} else if err := c.resumeOffset(ctx, u, size); err != nil {
+		return "", err
+	}
+	method := u.Method
+	if method == "" {
+		method = http.MethodPut
+	}
+	contentType := u.ContentType
+	if contentType == "" {
+		contentType = "application/octet-stream"
+	}
+	chunkSize := u.ChunkSize
+	if chunkSize <= 0 {
+		chunkSize = DefaultChunkSize
+	}
+
+	msg := ""
+	for u.Offset < size {
+		/*
+		 * First we will read the chunk
+		 * Then we will send the chunk
+		 * Then we will advance the offset
+		 */
+		//reading the chunk
+		n := chunkSize
+		if size-u.Offset < n {
+			n = size - u.Offset
+		}
+		chunk := make([]byte, n)
+		read, err := content.ReadAt(chunk, u.Offset)
+		if read != len(chunk) {
+			//the content is shorter than its size
+			if err == nil || err == io.EOF {
+				err = io.ErrUnexpectedEOF
+			}
+			return "", err
+		}
+
+		//sending the chunk
+		req, err := http.NewRequest(method, u.URL, bytes.NewReader(chunk))
+		if err != nil {
+			return "", err
+		}
+		req = req.WithContext(ctx)
+		req.Header.Set("Content-Type", contentType)
+		req.Header.Set("Content-Range", "bytes "+strconv.FormatInt(u.Offset, 10)+"-"+strconv.FormatInt(u.Offset+n-1, 10)+"/"+strconv.FormatInt(size, 10))
+		req.Header.Set(UploadIDHeader, u.ID)
+		req.Header.Set(IdempotencyKeyHeader, u.ID+"-"+strconv.FormatInt(u.Offset, 10))
+		res, apiErr, err := c.send(req, c.Do)
+		if err != nil {
+			return "", err
+		}
+		var target interface{}
+		if u.Offset+n == size {
+			target = out
+		}
+		msg, err = c.decodeJSON(res, apiErr, target)
+		if err != nil {
+			return "", err
+		}
+
+		//advancing the offset
+		u.Offset += n
+		if u.Progress != nil {
+			u.Progress(u.Offset)
+		}
+	}
+	return msg, nil
+}
+
+//resumeOffset sets the offset of the upload to the one received by the service if the service sends it
+func (c *Client) resumeOffset(ctx context.Context, u *ChunkedUpload, size int64) error {
+	req, err := http.NewRequest(http.MethodHead, u.URL, nil)
+	if err != nil {
+		return err
+	}
+	req = req.WithContext(ctx)
+	req.Header.Set(UploadIDHeader, u.ID)
+	res, _, err := c.send(req, c.Do)
+	if err != nil {
+		return err
+	}
+	res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		return nil
+	}
+	if offset, err := strconv.ParseInt(res.Header.Get(UploadOffsetHeader), 10, 64); err == nil && offset >= 0 && offset <= size {
+		u.Offset = offset
+	}
+	return nil
+}
@@ -0,0 +1,243 @@
+// Copyright 2019 Cuttle.ai. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package platform
+
+import (
+	"context"
+	"encoding/json"
+	"reflect"
+
+	"github.com/cuttle-ai/brain/log"
+	"github.com/cuttle-ai/go-sdk/httpclient"
+	"github.com/cuttle-ai/go-sdk/tracing"
+	"github.com/hashicorp/consul/api"
+)
+
+//Strategy is how the instances of a service are called
+type Strategy int
+
+const (
+	//FirstSuccess calls the instances one after the other till one of them succeeds.
+	//The calls are failed over to the next instance as per httpclient.FailOver
+	FirstSuccess Strategy = iota
+	//Broadcast calls all the instances one after the other and fails if any of them fails
+	Broadcast
+	//Quorum calls all the instances one after the other and succeeds if the quorum of them succeed
+	Quorum
+)
+
+//Call is a call to an operation of a platform service
+type Call struct {
+	//Service is the name with which the service is registered in the discovery service
+	Service string
+	//Operation is the name of the sdk operation like datastores.ListDatastores with which the call is traced and measured
+	Operation string
+	//Method is the http method of the call
+	Method string
+	//Path is the path of the api in the instances
+	Path string
+	//Payload is sent as the json body if not nil
+	Payload interface{}
+	//Out is the pointer to which the data of the response is decoded if not nil
+	Out interface{}
+	//Element returns the pointer to which an element of the data array is decoded when the response is streamed
+	Element func() interface{}
+	//Each streams the response if not nil. It is called with every element decoded and the errors it returns stop the stream.
+	//Streams are failed over to the next instance only if nothing was delivered from the failed one
+	Each func(interface{}) error
+	//Strategy is how the instances are called
+	Strategy Strategy
+	//Hedged hedges the call across the instances if hedging is set for the operation. Only the reads can be hedged
+	//and they are sent without an idempotency key
+	Hedged bool
+	//Quorum is the number of instances that have to succeed with the Quorum strategy. Defaults to the majority
+	Quorum int
+	//Action describes the call in the logs, like remove the dict
+	Action string
+}
+
+//Invoke makes the call to the instances of its service as per its strategy.
+//The request id, trace context and idempotency key in ctx are sent to every instance called, they are generated if missing.
+//The hedged calls are reads and are sent without the idempotency key.
+//The time left till the deadline of ctx is shared by the instances yet to be called
+func (p *Platform) Invoke(ctx context.Context, call Call) (err error) {
+	ctx, span := tracing.Start(ctx, call.Operation)
+	ctx = httpclient.WithOperation(ctx, call.Service, call.Operation)
+	defer func() {
+		span.RecordError(err)
+		span.End()
+	}()
+
+	/*
+	 * First we will get the instances of the service from discovery service
+	 * Then will call them as per the strategy
+	 */
+	ctx = httpclient.EnsureRequestContext(ctx)
+	if call.Hedged {
+		//the hedged reads can reach several instances at once, so a key would make them conflict
+		ctx = httpclient.WithoutIdempotencyKey(ctx)
+	} else {
+		ctx = httpclient.EnsureIdempotencyKey(ctx)
+	}
+	l := httpclient.RequestLogger(ctx, p.Logger)
+
+	//getting the instances
+	svs, err := p.Resolver.GetServices(ctx, call.Service, l)
+	if err != nil {
+		//error while getting the services from the discovery
+		l.Error("error while getting the list of", call.Service, "from discovery service")
+		return err
+	}
+
+	//calling the instances
+	switch {
+	case call.Each != nil:
+		return p.stream(ctx, l, call, svs)
+	case call.Strategy == FirstSuccess:
+		return p.firstSuccess(ctx, l, call, svs)
+	}
+	return p.broadcast(ctx, l, call, svs)
+}
+
+//instance calls an instance of the service decoding the data of the response to out
+func (p *Platform) instance(ctx context.Context, l log.Log, call Call, v *api.AgentService, out interface{}) error {
+	targetURL := httpclient.InstanceURL(v.Address, v.Port, call.Path)
+	l.Info("going to", call.Action, "at", targetURL)
+	msg, err := p.Client.DoJSON(ctx, call.Method, targetURL, call.Payload, out)
+	if err != nil {
+		//errors of the hedged calls cancelled are not logged
+		if ctx.Err() == nil {
+			l.Error("error while trying to", call.Action, "at", targetURL, err)
+		}
+		return err
+	}
+	l.Info("got the response message from", call.Service, "at", targetURL, msg)
+	return nil
+}
+
+//firstSuccess calls the instances one after the other till one of them succeeds, hedging the call if allowed.
+//The data of the successful instance is set to the call's out
+func (p *Platform) firstSuccess(ctx context.Context, l log.Log, call Call, svs []*api.AgentService) error {
+	if len(svs) == 0 {
+		return httpclient.NoInstancesError(call.Service)
+	}
+	outs := make([]interface{}, len(svs))
+	do := func(ctx context.Context, i int) error {
+		//every instance gets an out of its own since the hedged calls decode concurrently
+		outs[i] = newOut(call.Out)
+		return p.instance(ctx, l, call, svs[i], outs[i])
+	}
+	if call.Hedged {
+		i, err := httpclient.FirstSuccess(ctx, len(svs), do)
+		if err != nil {
+			return err
+		}
+		setOut(call.Out, outs[i])
+		return nil
+	}
+
+	var lastErr error
+	for i := range svs {
+		callCtx, cancel := httpclient.BudgetContext(ctx, len(svs)-i)
+		err := do(callCtx, i)
+		cancel()
+		if err == nil {
+			setOut(call.Out, outs[i])
+			return nil
+		}
+		if !httpclient.FailOver(ctx, err) {
+			return err
+		}
+		//we will try the next instance
+		lastErr = err
+	}
+	return lastErr
+}
+
+//broadcast calls all the instances one after the other. It fails with the last error if any of them fails
+//or for the Quorum strategy if less than the quorum of them succeed. The data of the last successful instance is set to the call's out
+func (p *Platform) broadcast(ctx context.Context, l log.Log, call Call, svs []*api.AgentService) error {
+	quorum := len(svs)
+	if call.Strategy == Quorum {
+		quorum = call.Quorum
+		if quorum <= 0 {
+			quorum = len(svs)/2 + 1
+		}
+	}
+
+	var lastErr error
+	succeeded := 0
+	for i, v := range svs {
+		out := newOut(call.Out)
+		callCtx, cancel := httpclient.BudgetContext(ctx, len(svs)-i)
+		err := p.instance(callCtx, l, call, v, out)
+		cancel()
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		succeeded++
+		setOut(call.Out, out)
+	}
+	if succeeded >= quorum {
+		return nil
+	}
+	if lastErr == nil {
+		return httpclient.NoInstancesError(call.Service)
+	}
+	return lastErr
+}
+
+//stream streams the data from the instances one after the other till one of them succeeds.
+//The stream is failed over as per httpclient.FailOver only if the instance failed before delivering anything
+func (p *Platform) stream(ctx context.Context, l log.Log, call Call, svs []*api.AgentService) error {
+	lastErr := httpclient.NoInstancesError(call.Service)
+	for i, v := range svs {
+		targetURL := httpclient.InstanceURL(v.Address, v.Port, call.Path)
+		l.Info("going to", call.Action, "at", targetURL)
+		delivered := 0
+		callCtx, cancel := httpclient.BudgetContext(ctx, len(svs)-i)
+		msg, err := p.Client.StreamJSON(callCtx, call.Method, targetURL, call.Payload, func(dec *json.Decoder) error {
+			e := call.Element()
+			if err := dec.Decode(e); err != nil {
+				return err
+			}
+			delivered++
+			return call.Each(e)
+		})
+		cancel()
+		if err != nil {
+			//error while streaming from the instance
+			l.Error("error while trying to", call.Action, "at", targetURL, err)
+			if delivered == 0 && httpclient.FailOver(ctx, err) {
+				//we will try the next instance since nothing was delivered from this one
+				lastErr = err
+				continue
+			}
+			return err
+		}
+
+		//got the response
+		l.Info("got the response message from", call.Service, "at", targetURL, msg)
+		return nil
+	}
+	return lastErr
+}
+
+//newOut returns a new pointer of the type of out to decode the data of an instance. It is nil if out is nil
+func newOut(out interface{}) interface{} {
+	if out == nil {
+		return nil
+	}
+	return reflect.New(reflect.TypeOf(out).Elem()).Interface()
+}
+
+//setOut sets the data decoded from an instance to out
+func setOut(out, decoded interface{}) {
+	if out == nil {
+		return
+	}
+	reflect.ValueOf(out).Elem().Set(reflect.ValueOf(decoded).Elem())
+}
@@ -0,0 +1,157 @@
+// Copyright 2019 Cuttle.ai. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package platform_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/cuttle-ai/brain/log"
+	"github.com/cuttle-ai/go-sdk/httpclient"
+	"github.com/cuttle-ai/go-sdk/internal/platform"
+	"github.com/hashicorp/consul/api"
+)
+
+//instances is the resolver returning the same instances for every service
+type instances []*api.AgentService
+
+func (s instances) GetServices(ctx context.Context, name string, l log.Log) ([]*api.AgentService, error) {
+	return s, nil
+}
+
+func TestInvoke(t *testing.T) {
+	newInstance := func(name string, status int) (*api.AgentService, func()) {
+		ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(status)
+			json.NewEncoder(w).Encode(map[string]interface{}{"Data": name, "Message": "called " + name})
+		}))
+		u, _ := url.Parse(ts.URL)
+		port, _ := strconv.Atoi(u.Port())
+		return &api.AgentService{Address: u.Hostname(), Port: port}, ts.Close
+	}
+	up, closeUp := newInstance("up", http.StatusOK)
+	defer closeUp()
+	down, closeDown := newInstance("down", http.StatusServiceUnavailable)
+	defer closeDown()
+	other, closeOther := newInstance("other", http.StatusOK)
+	defer closeOther()
+
+	cases := []struct {
+		name      string
+		instances instances
+		strategy  platform.Strategy
+		quorum    int
+		fails     bool
+		out       string
+	}{
+		{"first success fails over", instances{down, up, other}, platform.FirstSuccess, 0, false, "up"},
+		{"first success without instances", instances{}, platform.FirstSuccess, 0, true, ""},
+		{"broadcast fails if any fails", instances{up, down, other}, platform.Broadcast, 0, true, "other"},
+		{"broadcast without instances", instances{}, platform.Broadcast, 0, false, ""},
+		{"quorum of the majority", instances{up, down, other}, platform.Quorum, 0, false, "other"},
+		{"quorum of all", instances{up, down, other}, platform.Quorum, 3, true, "other"},
+	}
+	for _, c := range cases {
+		p := &platform.Platform{Resolver: c.instances, Logger: log.NewLogger()}
+		p.BuildClient()
+		out := ""
+		err := p.Invoke(context.Background(), platform.Call{
+			Service:   "Test-Service",
+			Operation: "test.Invoke",
+			Method:    http.MethodGet,
+			Path:      "/",
+			Out:       &out,
+			Strategy:  c.strategy,
+			Quorum:    c.quorum,
+			Action:    "test the strategy",
+		})
+		if (err != nil) != c.fails {
+			t.Error(c.name, "expected to fail", c.fails, "got", err)
+		}
+		if out != c.out {
+			t.Error(c.name, "expected the data of", c.out, "got", out)
+		}
+	}
+}
+
+func TestInvokeDeadline(t *testing.T) {
+	slow := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-time.After(2 * time.Second):
+		case <-r.Context().Done():
+		}
+	}))
+	defer slow.Close()
+	var calls int32
+	fast := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		json.NewEncoder(w).Encode(httpclient.Message{Message: "done"})
+	}))
+	defer fast.Close()
+	instance := func(ts *httptest.Server) *api.AgentService {
+		u, _ := url.Parse(ts.URL)
+		port, _ := strconv.Atoi(u.Port())
+		return &api.AgentService{Address: u.Hostname(), Port: port}
+	}
+	p := &platform.Platform{Resolver: instances{instance(slow), instance(fast)}, Logger: log.NewLogger()}
+	p.BuildClient()
+	call := platform.Call{Service: "Test-Service", Operation: "test.Deadline", Method: http.MethodGet, Path: "/", Action: "test the deadline"}
+
+	//the caller giving up isn't failed over to the next instance
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	err := p.Invoke(ctx, call)
+	cancel()
+	if !errors.Is(err, context.DeadlineExceeded) || errors.Is(err, httpclient.ErrUnavailable) {
+		t.Error("expected the call to fail with the deadline of the caller, got", err)
+	}
+	if atomic.LoadInt32(&calls) != 0 {
+		t.Error("expected the call not to be failed over after the deadline of the caller, got calls", calls)
+	}
+
+	//the instance running out of its share of the time is failed over
+	ctx, cancel = context.WithTimeout(context.Background(), 2*time.Second)
+	err = p.Invoke(ctx, call)
+	cancel()
+	if err != nil || atomic.LoadInt32(&calls) != 1 {
+		t.Error("expected the call to be failed over once the slow instance used its share of the time, got", err, calls)
+	}
+}
+
+func TestInvokeHedgedIdempotencyKey(t *testing.T) {
+	keys := make(chan string, 10)
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		keys <- r.Header.Get(httpclient.IdempotencyKeyHeader)
+		json.NewEncoder(w).Encode(httpclient.Message{Message: "done"})
+	}))
+	defer ts.Close()
+	u, _ := url.Parse(ts.URL)
+	port, _ := strconv.Atoi(u.Port())
+	p := &platform.Platform{Resolver: instances{{Address: u.Hostname(), Port: port}}, Logger: log.NewLogger()}
+	p.BuildClient()
+
+	call := platform.Call{Service: "Test-Service", Operation: "test.Read", Method: http.MethodPost, Path: "/", Hedged: true, Action: "test the read"}
+	if err := p.Invoke(context.Background(), call); err != nil {
+		t.Fatal("error while making the hedged read", err)
+	}
+	if key := <-keys; key != "" {
+		t.Error("expected the hedged read to be sent without an idempotency key, got", key)
+	}
+
+	call.Hedged = false
+	if err := p.Invoke(context.Background(), call); err != nil {
+		t.Fatal("error while making the call", err)
+	}
+	if key := <-keys; key == "" {
+		t.Error("expected the call not hedged to be sent with an idempotency key")
+	}
+}
@@ -0,0 +1,53 @@
+// Copyright 2019 Cuttle.ai. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+//Package platform has what the service packages of the sdk share to call the platform services
+package platform
+
+import (
+	"github.com/cuttle-ai/brain/appctx"
+	"github.com/cuttle-ai/brain/log"
+	"github.com/cuttle-ai/go-sdk/discovery"
+	"github.com/cuttle-ai/go-sdk/httpclient"
+	"github.com/hashicorp/consul/api"
+)
+
+//TokenKey is the cookie in which the access token is sent to the platform services
+const TokenKey = "auth-token"
+
+//Platform has the resolver, logger, auth and http settings with which the platform services are called
+type Platform struct {
+	//Resolver finds the instances of the services
+	Resolver discovery.Resolver
+	//Logger is the logger to which the calls are logged along with their request ids
+	Logger log.Log
+	//AccessToken is the token with which the calls are authenticated
+	AccessToken string
+	//Middlewares are applied to the calls after the global ones
+	Middlewares []httpclient.Middleware
+	//MaxResponseSize if > 0 overrides the max size of the json responses read
+	MaxResponseSize int64
+	//Client is the http client with which all the instances are called. It is built from the settings above with BuildClient
+	Client *httpclient.Client
+}
+
+//FromAppContext returns the platform with the discovery service, token and logger of the app context
+func FromAppContext(appCtx appctx.AppContext) *Platform {
+	config := api.DefaultConfig()
+	config.Address = appCtx.DiscoveryAddress()
+	config.Token = appCtx.DiscoveryToken()
+	return &Platform{
+		Resolver:    discovery.NewConsul(config),
+		Logger:      appCtx.Logger(),
+		AccessToken: appCtx.AccessToken(),
+	}
+}
+
+//BuildClient builds the http client of the platform with its access token, middlewares and max response size.
+//It is called once all of them are set
+func (p *Platform) BuildClient() {
+	p.Client = httpclient.NewClient("", p.AccessToken, TokenKey)
+	p.Client.Use(p.Middlewares...)
+	p.Client.SetMaxResponseSize(p.MaxResponseSize)
+}
@@ -0,0 +1,95 @@
+// Copyright 2019 Cuttle.ai. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+//Package sdktest has the helpers to test the service packages of the sdk against the fixtures recorded from the platform
+package sdktest
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"sync"
+	"testing"
+
+	"github.com/cuttle-ai/brain/appctx"
+	"github.com/cuttle-ai/brain/env"
+	"github.com/cuttle-ai/brain/log"
+	"github.com/cuttle-ai/go-sdk/httpclient"
+	"github.com/hashicorp/consul/api"
+)
+
+//RecordEnv is the env variable which when set to 1 makes the tests record their fixtures from the platform
+//configured in the .env file instead of replaying them
+const RecordEnv = "RECORD_FIXTURES"
+
+var (
+	once    sync.Once
+	mu      sync.Mutex
+	current *httpclient.Fixture
+)
+
+//AppCtx returns the app context with which the test calls the service.
+//If RecordEnv is set, the calls are made to the platform configured in the .env file and recorded to the fixture.
+//Otherwise they are replayed from the fixture with a fake discovery service having an instance of the service.
+//The returned function saves the recording and must be called at the end of the test
+func AppCtx(t *testing.T, fixture, service string) (appctx.AppContext, func()) {
+	t.Helper()
+	once.Do(func() {
+		httpclient.Use(func(next http.RoundTripper) http.RoundTripper {
+			return httpclient.RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+				mu.Lock()
+				f := current
+				mu.Unlock()
+				if f == nil {
+					return next.RoundTrip(req)
+				}
+				return f.Middleware()(next).RoundTrip(req)
+			})
+		})
+	})
+
+	if os.Getenv(RecordEnv) == "1" {
+		f, err := httpclient.NewFixture(fixture, httpclient.Record)
+		if err != nil {
+			t.Fatal("error while creating the fixture", err)
+		}
+		use(f)
+		env.LoadEnv(log.NewLogger())
+		appCtx := appctx.NewAppCtx(os.Getenv("APP_TOKEN"), os.Getenv("DISCOVERY_TOKEN"), os.Getenv("DISCOVERY_URL"))
+		return appCtx, func() {
+			use(nil)
+			if err := f.Save(); err != nil {
+				t.Error("error while saving the fixture", err)
+			}
+		}
+	}
+
+	f, err := httpclient.NewFixture(fixture, httpclient.Replay)
+	if err != nil {
+		t.Fatal("error while loading the fixture", err)
+	}
+	use(f)
+	discovery := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/agent/services" {
+			http.NotFound(w, r)
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]*api.AgentService{
+			service: {ID: service, Service: service, Address: "127.0.0.1", Port: 80},
+		})
+	}))
+	appCtx := appctx.NewAppCtx("test-token", "", discovery.URL)
+	return appCtx, func() {
+		use(nil)
+		discovery.Close()
+	}
+}
+
+//use sets the fixture through which the requests of the tests go
+func use(f *httpclient.Fixture) {
+	mu.Lock()
+	current = f
+	mu.Unlock()
+}
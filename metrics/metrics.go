@@ -0,0 +1,150 @@
+// Copyright 2019 Cuttle.ai. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+//Package metrics has the prometheus collectors of the sdk. The host application can register them with
+//the registry served by its /metrics handler
+package metrics
+
+import (
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const (
+	//OutcomeSuccess is the outcome of the requests that got a 2xx or 3xx response
+	OutcomeSuccess = "success"
+	//OutcomeClientError is the outcome of the requests that got a 4xx response
+	OutcomeClientError = "client_error"
+	//OutcomeServerError is the outcome of the requests that got a 5xx response
+	OutcomeServerError = "server_error"
+	//OutcomeError is the outcome of the requests that didn't get any response
+	OutcomeError = "error"
+)
+
+const (
+	//CircuitClosed is the state of the circuits letting the calls through
+	CircuitClosed = 0
+	//CircuitHalfOpen is the state of the circuits letting a trial call through
+	CircuitHalfOpen = 1
+	//CircuitOpen is the state of the circuits rejecting the calls
+	CircuitOpen = 2
+)
+
+var (
+	requests = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "cuttle",
+		Subsystem: "sdk",
+		Name:      "requests_total",
+		Help:      "Number of requests made by the sdk to the platform services including the retries",
+	}, []string{"service", "operation", "instance", "outcome"})
+
+	requestDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: "cuttle",
+		Subsystem: "sdk",
+		Name:      "request_duration_seconds",
+		Help:      "Latency of the requests made by the sdk to the platform services",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"service", "operation", "instance", "outcome"})
+
+	throttled = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "cuttle",
+		Subsystem: "sdk",
+		Name:      "throttled_total",
+		Help:      "Number of calls throttled by the rate limits of the sdk",
+	}, []string{"service", "operation", "outcome"})
+
+	bulkheadInFlight = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: "cuttle",
+		Subsystem: "sdk",
+		Name:      "bulkhead_in_flight",
+		Help:      "Number of requests in flight to the platform services holding a slot of their bulkhead",
+	}, []string{"service"})
+
+	bulkheadRejected = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "cuttle",
+		Subsystem: "sdk",
+		Name:      "bulkhead_rejected_total",
+		Help:      "Number of calls rejected since the bulkhead of the service was full",
+	}, []string{"service", "operation"})
+
+	discoveredInstances = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: "cuttle",
+		Subsystem: "sdk",
+		Name:      "discovered_instances",
+		Help:      "Number of instances of the platform services found in the last discovery lookup",
+	}, []string{"service"})
+
+	circuitState = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: "cuttle",
+		Subsystem: "sdk",
+		Name:      "circuit_state",
+		Help:      "State of the circuit breakers of the platform services. 0 is closed, 1 is half open and 2 is open",
+	}, []string{"service"})
+)
+
+//Collectors returns the collectors of the sdk
+func Collectors() []prometheus.Collector {
+	return []prometheus.Collector{requests, requestDuration, throttled, bulkheadInFlight, bulkheadRejected, discoveredInstances, circuitState}
+}
+
+//Register registers the collectors of the sdk with the registerer
+func Register(reg prometheus.Registerer) error {
+	for _, c := range Collectors() {
+		if err := reg.Register(c); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+//Outcome returns the outcome label for the status code of a response. A status code of 0 means no response was received
+func Outcome(statusCode int) string {
+	switch {
+	case statusCode == 0:
+		return OutcomeError
+	case statusCode >= 500:
+		return OutcomeServerError
+	case statusCode >= 400:
+		return OutcomeClientError
+	}
+	return OutcomeSuccess
+}
+
+//ObserveRequest records a request made to an instance of the service for the sdk operation
+func ObserveRequest(service, operation, instance, outcome string, d time.Duration) {
+	requests.WithLabelValues(service, operation, instance, outcome).Inc()
+	requestDuration.WithLabelValues(service, operation, instance, outcome).Observe(d.Seconds())
+}
+
+//SetDiscoveredInstances records the number of instances of the service found in discovery
+func SetDiscoveredInstances(service string, n int) {
+	discoveredInstances.WithLabelValues(service).Set(float64(n))
+}
+
+//ObserveThrottled records a call throttled by the rate limit of the service's operation.
+//rejected is true if the call was rejected instead of waiting for its turn
+func ObserveThrottled(service, operation string, rejected bool) {
+	outcome := "waited"
+	if rejected {
+		outcome = "rejected"
+	}
+	throttled.WithLabelValues(service, operation, outcome).Inc()
+}
+
+//SetBulkheadInFlight records the number of requests in flight holding a slot of the service's bulkhead
+func SetBulkheadInFlight(service string, n int) {
+	bulkheadInFlight.WithLabelValues(service).Set(float64(n))
+}
+
+//ObserveBulkheadRejected records a call to the service for the sdk operation rejected by the service's full bulkhead
+func ObserveBulkheadRejected(service, operation string) {
+	bulkheadRejected.WithLabelValues(service, operation).Inc()
+}
+
+//SetCircuitState records the state like CircuitOpen of the circuit breaker guarding the calls to the service.
+//It is set by the breakers wrapping the calls of the sdk, like the middlewares registered with httpclient.Use
+func SetCircuitState(service string, state int) {
+	circuitState.WithLabelValues(service).Set(float64(state))
+}
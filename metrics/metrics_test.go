@@ -0,0 +1,38 @@
+// Copyright 2019 Cuttle.ai. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package metrics_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/cuttle-ai/go-sdk/metrics"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+func TestRegister(t *testing.T) {
+	reg := prometheus.NewRegistry()
+	if err := metrics.Register(reg); err != nil {
+		t.Fatal("error while registering the collectors", err)
+	}
+	metrics.ObserveRequest("Brain-Data-Integeration-Service", "datastores.ListDatastores", "127.0.0.1:8080", metrics.Outcome(503), time.Millisecond)
+	metrics.SetDiscoveredInstances("Brain-Data-Integeration-Service", 2)
+	metrics.ObserveBulkheadRejected("Brain-Octopus-Service", "octopus.UpdateDict")
+	metrics.SetCircuitState("Brain-Octopus-Service", metrics.CircuitOpen)
+
+	families, err := reg.Gather()
+	if err != nil {
+		t.Fatal("error while gathering the metrics", err)
+	}
+	found := map[string]bool{}
+	for _, f := range families {
+		found[f.GetName()] = true
+	}
+	for _, name := range []string{"cuttle_sdk_requests_total", "cuttle_sdk_request_duration_seconds", "cuttle_sdk_bulkhead_rejected_total", "cuttle_sdk_discovered_instances", "cuttle_sdk_circuit_state"} {
+		if !found[name] {
+			t.Error("expected the metric to be gathered", name)
+		}
+	}
+}
@@ -0,0 +1,54 @@
+// Copyright 2019 Cuttle.ai. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package sdk
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/cuttle-ai/brain/models"
+	"github.com/cuttle-ai/go-sdk/internal/platform"
+)
+
+//NotificationsClient is the client sending the notifications to the user's websocket clients through the websockets servers
+type NotificationsClient struct {
+	p *platform.Platform
+}
+
+//send sends the notification to any one of the websockets servers
+func (c *NotificationsClient) send(ctx context.Context, n models.Notification) error {
+	return c.p.Invoke(ctx, platform.Call{
+		Service:   "Brain-Websockets-Server",
+		Operation: "websockets.SendNotification",
+		Method:    http.MethodPost,
+		Path:      "/notification/send",
+		Payload:   n,
+		Action:    "send notification to websockets server",
+	})
+}
+
+//SendInfo will send a info notification to the user's websocket clients
+func (c *NotificationsClient) SendInfo(ctx context.Context, n models.Notification) error {
+	n.Event = models.InfoNotification
+	return c.send(ctx, n)
+}
+
+//SendError will send a error notification to the user's websocket clients
+func (c *NotificationsClient) SendError(ctx context.Context, n models.Notification) error {
+	n.Event = models.ErrorNotification
+	return c.send(ctx, n)
+}
+
+//SendSuccess will send a success notification to the user's websocket clients
+func (c *NotificationsClient) SendSuccess(ctx context.Context, n models.Notification) error {
+	n.Event = models.SuccessNotification
+	return c.send(ctx, n)
+}
+
+//SendAction will send an action notification to websocket server
+func (c *NotificationsClient) SendAction(ctx context.Context, n models.Notification) error {
+	n.Event = models.ActionNotification
+	return c.send(ctx, n)
+}
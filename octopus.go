@@ -0,0 +1,41 @@
+// Copyright 2019 Cuttle.ai. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package sdk
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/cuttle-ai/go-sdk/internal/platform"
+)
+
+//OctopusClient is the client of the octopus services
+type OctopusClient struct {
+	p *platform.Platform
+}
+
+//RemoveDict will remove the dict corresponding to a user from the cache of every octopus service
+func (c *OctopusClient) RemoveDict(ctx context.Context) error {
+	return c.p.Invoke(ctx, platform.Call{
+		Service:   "Brain-Octopus-Service",
+		Operation: "octopus.RemoveDict",
+		Method:    http.MethodGet,
+		Path:      "/dict/remove",
+		Strategy:  platform.Broadcast,
+		Action:    "remove the dict",
+	})
+}
+
+//UpdateDict will update the dict corresponding to a user in the cache of every octopus service with updated datasets
+func (c *OctopusClient) UpdateDict(ctx context.Context) error {
+	return c.p.Invoke(ctx, platform.Call{
+		Service:   "Brain-Octopus-Service",
+		Operation: "octopus.UpdateDict",
+		Method:    http.MethodGet,
+		Path:      "/dict/update",
+		Strategy:  platform.Broadcast,
+		Action:    "update the dict",
+	})
+}
@@ -0,0 +1,124 @@
+// Copyright 2019 Cuttle.ai. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+//Package sdk has the client to interact with the services of the cuttle platform.
+//The client holds the discovery resolver, auth, logger and http settings shared by the sub clients of the services.
+//The request id, trace context and idempotency key in the context given to the calls of the sub clients
+//are sent to the services, they are generated if missing.
+//
+//The tls, proxies, rate limits, bulkheads, hedging and the middlewares registered with httpclient.Use are set with the
+//functions of the httpclient package and are shared by all the clients of the process. Only the options of NewClient
+//are specific to a client
+package sdk
+
+import (
+	"github.com/cuttle-ai/brain/appctx"
+	"github.com/cuttle-ai/brain/log"
+	"github.com/cuttle-ai/go-sdk/discovery"
+	"github.com/cuttle-ai/go-sdk/httpclient"
+	"github.com/cuttle-ai/go-sdk/internal/platform"
+	"github.com/hashicorp/consul/api"
+)
+
+//Client is the client of the cuttle platform. It holds the discovery resolver and the http client with which its sub clients
+//call all the instances. It is safe for concurrent use and meant to be reused across the calls
+type Client struct {
+	datastores    *DatastoresClient
+	octopus       *OctopusClient
+	notifications *NotificationsClient
+}
+
+//Option configures the client
+type Option func(*platform.Platform)
+
+//WithAppContext makes the client use the discovery service, access token and logger of the app context
+func WithAppContext(appCtx appctx.AppContext) Option {
+	return func(p *platform.Platform) {
+		a := platform.FromAppContext(appCtx)
+		p.Resolver, p.AccessToken, p.Logger = a.Resolver, a.AccessToken, a.Logger
+	}
+}
+
+//WithDiscovery makes the client find the platform services in the consul agent at the address with the token
+func WithDiscovery(address, token string) Option {
+	return func(p *platform.Platform) {
+		config := api.DefaultConfig()
+		config.Address = address
+		config.Token = token
+		p.Resolver = discovery.NewConsul(config)
+	}
+}
+
+//WithResolver makes the client find the platform services with the resolver
+func WithResolver(r discovery.Resolver) Option {
+	return func(p *platform.Platform) {
+		p.Resolver = r
+	}
+}
+
+//WithAccessToken sets the token with which the calls to the platform services are authenticated
+func WithAccessToken(token string) Option {
+	return func(p *platform.Platform) {
+		p.AccessToken = token
+	}
+}
+
+//WithLogger sets the logger to which the calls are logged
+func WithLogger(l log.Log) Option {
+	return func(p *platform.Platform) {
+		p.Logger = l
+	}
+}
+
+//WithMiddleware adds the middlewares that will see only the requests made by the client.
+//They are applied inside the ones registered with httpclient.Use
+func WithMiddleware(mws ...httpclient.Middleware) Option {
+	return func(p *platform.Platform) {
+		p.Middlewares = append(p.Middlewares, mws...)
+	}
+}
+
+//WithMaxResponseSize sets the maximum size of the json responses read by the client
+//overriding the one set with httpclient.SetMaxResponseSize
+func WithMaxResponseSize(n int64) Option {
+	return func(p *platform.Platform) {
+		p.MaxResponseSize = n
+	}
+}
+
+//NewClient returns the client of the platform configured with the options.
+//By default the services are found in the consul agent of the environment and the calls are logged to a new logger
+func NewClient(opts ...Option) *Client {
+	p := &platform.Platform{}
+	for _, opt := range opts {
+		opt(p)
+	}
+	if p.Resolver == nil {
+		p.Resolver = discovery.NewConsul(api.DefaultConfig())
+	}
+	if p.Logger == nil {
+		p.Logger = log.NewLogger()
+	}
+	p.BuildClient()
+	return &Client{
+		datastores:    &DatastoresClient{p: p},
+		octopus:       &OctopusClient{p: p},
+		notifications: &NotificationsClient{p: p},
+	}
+}
+
+//Datastores returns the client of the data-integration services managing the datastores
+func (c *Client) Datastores() *DatastoresClient {
+	return c.datastores
+}
+
+//Octopus returns the client of the octopus services
+func (c *Client) Octopus() *OctopusClient {
+	return c.octopus
+}
+
+//Notifications returns the client sending the notifications to the user's websocket clients
+func (c *Client) Notifications() *NotificationsClient {
+	return c.notifications
+}
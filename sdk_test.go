@@ -0,0 +1,50 @@
+// Copyright 2019 Cuttle.ai. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package sdk_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"testing"
+
+	"github.com/cuttle-ai/brain/log"
+	sdk "github.com/cuttle-ai/go-sdk"
+	"github.com/cuttle-ai/go-sdk/httpclient"
+	"github.com/hashicorp/consul/api"
+)
+
+//countingResolver counts the lookups made through the resolver of the instance
+type countingResolver struct {
+	instance *api.AgentService
+	lookups  int
+}
+
+func (c *countingResolver) GetServices(ctx context.Context, name string, l log.Log) ([]*api.AgentService, error) {
+	c.lookups++
+	return []*api.AgentService{c.instance}, nil
+}
+
+func TestClient(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(httpclient.Message{Message: "removed the dict"})
+	}))
+	defer ts.Close()
+	u, _ := url.Parse(ts.URL)
+	port, _ := strconv.Atoi(u.Port())
+	resolver := &countingResolver{instance: &api.AgentService{Address: u.Hostname(), Port: port}}
+	client := sdk.NewClient(sdk.WithResolver(resolver), sdk.WithAccessToken("token"))
+	for i := 0; i < 2; i++ {
+		if err := client.Octopus().RemoveDict(context.Background()); err != nil {
+			t.Error("error while removing the dict from octopus service", err)
+		}
+	}
+	if resolver.lookups != 2 {
+		t.Error("expected the services to be found with the resolver of the client, got lookups", resolver.lookups)
+	}
+}
@@ -5,23 +5,20 @@
 package datastores_test
 
 import (
-	"os"
 	"testing"
 
-	"github.com/cuttle-ai/brain/appctx"
-	"github.com/cuttle-ai/brain/env"
-	"github.com/cuttle-ai/brain/log"
+	"github.com/cuttle-ai/go-sdk/internal/sdktest"
 	"github.com/cuttle-ai/go-sdk/services/datastores"
 )
 
 func TestListDatastores(t *testing.T) {
-	env.LoadEnv(log.NewLogger())
-	appToken := os.Getenv("APP_TOKEN")
-	discoveryURL := os.Getenv("DISCOVERY_URL")
-	discoveryToken := os.Getenv("DISCOVERY_TOKEN")
-	appCtx := appctx.NewAppCtx(appToken, discoveryToken, discoveryURL)
-	_, err := datastores.ListDatastores(appCtx)
+	appCtx, done := sdktest.AppCtx(t, "testdata/list_datastores.json", "Brain-Data-Integeration-Service")
+	defer done()
+	list, err := datastores.ListDatastores(appCtx)
 	if err != nil {
 		t.Error("error while getting the list of datastores", err)
 	}
+	if len(list) == 0 {
+		t.Error("expected the list of datastores to be non empty")
+	}
 }
@@ -5,21 +5,15 @@
 package octopus_test
 
 import (
-	"os"
 	"testing"
 
-	"github.com/cuttle-ai/brain/appctx"
-	"github.com/cuttle-ai/brain/env"
-	"github.com/cuttle-ai/brain/log"
+	"github.com/cuttle-ai/go-sdk/internal/sdktest"
 	"github.com/cuttle-ai/go-sdk/services/octopus"
 )
 
 func TestRemoveDict(t *testing.T) {
-	env.LoadEnv(log.NewLogger())
-	appToken := os.Getenv("APP_TOKEN")
-	discoveryURL := os.Getenv("DISCOVERY_URL")
-	discoveryToken := os.Getenv("DISCOVERY_TOKEN")
-	appCtx := appctx.NewAppCtx(appToken, discoveryToken, discoveryURL)
+	appCtx, done := sdktest.AppCtx(t, "testdata/remove_dict.json", "Brain-Octopus-Service")
+	defer done()
 	err := octopus.RemoveDict(appCtx)
 	if err != nil {
 		t.Error("error while removing the dict from octopus service", err)
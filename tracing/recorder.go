@@ -0,0 +1,92 @@
+// Copyright 2019 Cuttle.ai. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package tracing
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+//RecordedSpan is a span recorded by the Recorder
+type RecordedSpan struct {
+	//Name of the span
+	Name string
+	//Parent is the span in which this span was started. It will be nil for the root spans
+	Parent *RecordedSpan
+	//Attributes set on the span
+	Attributes map[string]interface{}
+	//Err is the error recorded in the span
+	Err error
+	//StartTime is the time at which the span was started
+	StartTime time.Time
+	//EndTime is the time at which the span was ended
+	EndTime  time.Time
+	recorder *Recorder
+}
+
+//SetAttribute sets an attribute of the span
+func (s *RecordedSpan) SetAttribute(key string, value interface{}) {
+	s.recorder.mu.Lock()
+	s.Attributes[key] = value
+	s.recorder.mu.Unlock()
+}
+
+//RecordError records the error of the span
+func (s *RecordedSpan) RecordError(err error) {
+	if err == nil {
+		return
+	}
+	s.recorder.mu.Lock()
+	s.Err = err
+	s.recorder.mu.Unlock()
+}
+
+//End ends the span and adds it to the spans of the recorder
+func (s *RecordedSpan) End() {
+	s.recorder.mu.Lock()
+	s.EndTime = time.Now()
+	s.recorder.spans = append(s.recorder.spans, s)
+	s.recorder.mu.Unlock()
+}
+
+//Recorder is a tracer that keeps the spans ended in memory. It is meant to be used in tests
+type Recorder struct {
+	mu    sync.Mutex
+	spans []*RecordedSpan
+}
+
+//NewRecorder returns a new recorder
+func NewRecorder() *Recorder {
+	return &Recorder{}
+}
+
+//Start starts a span as a child of the recorded span in the context if any
+func (r *Recorder) Start(ctx context.Context, name string) (context.Context, Span) {
+	s := &RecordedSpan{
+		Name:       name,
+		Attributes: map[string]interface{}{},
+		StartTime:  time.Now(),
+		recorder:   r,
+	}
+	if p, ok := SpanFromContext(ctx).(*RecordedSpan); ok && p.recorder == r {
+		s.Parent = p
+	}
+	return ContextWithSpan(ctx, s), s
+}
+
+//Spans returns the spans ended so far in the order in which they ended
+func (r *Recorder) Spans() []*RecordedSpan {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]*RecordedSpan{}, r.spans...)
+}
+
+//Reset removes the spans recorded so far
+func (r *Recorder) Reset() {
+	r.mu.Lock()
+	r.spans = nil
+	r.mu.Unlock()
+}
@@ -0,0 +1,89 @@
+// Copyright 2019 Cuttle.ai. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+//Package tracing has the pluggable tracer used by the sdk to record spans for the operations done with
+//the cuttle platform services. By default the spans are not recorded anywhere
+package tracing
+
+import (
+	"context"
+	"sync"
+)
+
+//Span is a unit of work traced by the sdk
+type Span interface {
+	//SetAttribute sets an attribute of the span like the instance address or the status of the response
+	SetAttribute(key string, value interface{})
+	//RecordError records the error with which the work of the span failed. nil errors are ignored
+	RecordError(err error)
+	//End marks the end of the work of the span
+	End()
+}
+
+//Tracer starts the spans. The returned context carries the span so that the spans started
+//with it are its children
+type Tracer interface {
+	//Start starts a span with the given name as a child of the span in the context if any
+	Start(ctx context.Context, name string) (context.Context, Span)
+}
+
+type noopSpan struct{}
+
+func (noopSpan) SetAttribute(key string, value interface{}) {}
+
+func (noopSpan) RecordError(err error) {}
+
+func (noopSpan) End() {}
+
+type noopTracer struct{}
+
+func (noopTracer) Start(ctx context.Context, name string) (context.Context, Span) {
+	return ctx, noopSpan{}
+}
+
+//Noop is the tracer that doesn't record anything. It is the default tracer of the sdk
+var Noop Tracer = noopTracer{}
+
+var (
+	tracerMu sync.RWMutex
+	tracer   = Noop
+)
+
+//SetTracer sets the tracer to be used by the sdk. Setting nil will restore the Noop tracer
+func SetTracer(t Tracer) {
+	if t == nil {
+		t = Noop
+	}
+	tracerMu.Lock()
+	tracer = t
+	tracerMu.Unlock()
+}
+
+//GetTracer returns the tracer used by the sdk
+func GetTracer() Tracer {
+	tracerMu.RLock()
+	defer tracerMu.RUnlock()
+	return tracer
+}
+
+//Start starts a span with the tracer used by the sdk
+func Start(ctx context.Context, name string) (context.Context, Span) {
+	return GetTracer().Start(ctx, name)
+}
+
+type spanKey struct{}
+
+//ContextWithSpan returns a copy of the context carrying the span.
+//Tracer implementations can use it to link the child spans to their parent
+func ContextWithSpan(ctx context.Context, s Span) context.Context {
+	return context.WithValue(ctx, spanKey{}, s)
+}
+
+//SpanFromContext returns the span carried by the context. If the context has no span, a span that doesn't record anything is returned
+func SpanFromContext(ctx context.Context) Span {
+	if s, ok := ctx.Value(spanKey{}).(Span); ok {
+		return s
+	}
+	return noopSpan{}
+}